@@ -1,53 +1,40 @@
 package actions
 
+// Action is a single change of the buffer content. Every mutation of the
+// buffer is made of insertions and deletions of text, which makes any action
+// trivially invertible
 type Action struct {
-	Kind  Kind
-	Value string
-	Pos   struct {
-		X, Y int
-	}
+	Kind  Kind   `json:"kind"`
+	Value string `json:"value"`
+	Pos   Pos    `json:"pos"`
 }
 
-const (
-	// insertions
-	InsertRune = iota
-	InsertNL
-	InsertNLAbove
-	InsertNLUnder
-
-	// deletions
-	DeleteRuneBefore
-	DeleteRunUnder
-
-	// cursor movements
-	MoveCL
-	MoveCR
-	MoveCU
-	MoveCD
+// Pos is a position in the buffer, in runes
+type Pos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
 
-	// misc
-	SwitchMode
+const (
+	Insert = iota
+	Delete
 )
 
 type Kind string
 
 var Kinds = []Kind{
-	// insertions
-	InsertRune:    "InsertRune",
-	InsertNL:      "InsertNewLine",
-	InsertNLAbove: "InsertNewLineAbove",
-	InsertNLUnder: "InsertNewLineUnder",
-
-	// deletions
-	DeleteRuneBefore: "DeleteRuneBefore",
-	DeleteRunUnder:   "DeleteRuneUnder",
-
-	// cursor movements
-	MoveCL: "MoveCursorLeft",
-	MoveCR: "MoveCursorRight",
-	MoveCU: "MoveCursorUp",
-	MoveCD: "MoveCursorDown",
+	Insert: "Insert",
+	Delete: "Delete",
+}
 
-	// misc
-	SwitchMode: "SwitchMode",
+// Invert returns the action that cancels a
+func (a Action) Invert() Action {
+	inv := a
+	switch a.Kind {
+	case Kinds[Insert]:
+		inv.Kind = Kinds[Delete]
+	case Kinds[Delete]:
+		inv.Kind = Kinds[Insert]
+	}
+	return inv
 }
@@ -0,0 +1,228 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// History is an undo tree. Each state of the buffer is a node of the tree, and
+// going from a node to one of its children is done by applying the actions it
+// holds. Undoing and then making a new change creates a new branch, so nothing
+// is ever lost
+type History struct {
+	states  []*State // every state, indexed by their sequence number
+	current *State
+
+	pending Change // actions recorded since the last commit
+}
+
+// Change is a group of actions making a single logical edit, like a whole
+// insert session or a paste, that is undone and redone at once
+type Change struct {
+	Actions []Action `json:"actions"`
+	Cursor  Pos      `json:"cursor"` // where the cursor was when the change began
+}
+
+// State is a node of the undo tree
+type State struct {
+	Seq    int
+	Change // the change that leads from the parent to this state
+
+	parent   *State
+	children []*State
+	redo     *State // the child to go to on redo, the most recent by default
+}
+
+func NewHistory() *History {
+	root := &State{}
+	return &History{
+		states:  []*State{root},
+		current: root,
+	}
+}
+
+// Current returns the sequence number of the current state
+func (h *History) Current() int {
+	return h.current.Seq
+}
+
+// Last returns the sequence number of the most recent state
+func (h *History) Last() int {
+	return len(h.states) - 1
+}
+
+// Record adds a to the pending change. cursor is the position of the cursor
+// before a is done, and is kept if a is the first action of the change
+func (h *History) Record(a Action, cursor Pos) {
+	if len(h.pending.Actions) == 0 {
+		h.pending.Cursor = cursor
+	}
+	h.pending.Actions = append(h.pending.Actions, a)
+}
+
+// Commit closes the pending change and adds it as a new state on top of the
+// current one
+func (h *History) Commit() {
+	if len(h.pending.Actions) == 0 {
+		return
+	}
+
+	s := &State{
+		Seq:    len(h.states),
+		Change: h.pending,
+		parent: h.current,
+	}
+	h.pending = Change{}
+
+	h.states = append(h.states, s)
+	h.current.children = append(h.current.children, s)
+	h.current.redo = s
+	h.current = s
+}
+
+// Undo goes back to the parent state and returns the change to apply to the
+// buffer to get there
+func (h *History) Undo() (Change, bool) {
+	h.Commit()
+	if h.current.parent == nil {
+		return Change{}, false
+	}
+
+	s := h.current
+	h.current = s.parent
+	h.current.redo = s
+	return Change{Actions: invert(s.Actions), Cursor: s.Cursor}, true
+}
+
+// Redo goes to the most recently visited child state and returns the change
+// to apply to the buffer to get there
+func (h *History) Redo() (Change, bool) {
+	h.Commit()
+	if h.current.redo == nil {
+		return Change{}, false
+	}
+
+	h.current = h.current.redo
+	return h.current.Change, true
+}
+
+// Goto moves to the state with the given sequence number, whatever the branch
+// it is on, and returns the change to apply to the buffer to get there
+func (h *History) Goto(seq int) (Change, bool) {
+	h.Commit()
+	if seq < 0 || seq >= len(h.states) || seq == h.current.Seq {
+		return Change{}, false
+	}
+	target := h.states[seq]
+
+	// find the closest common ancestor of both states
+	ancestors := make(map[*State]bool)
+	for s := h.current; s != nil; s = s.parent {
+		ancestors[s] = true
+	}
+	var down []*State
+	common := target
+	for !ancestors[common] {
+		down = append(down, common)
+		common = common.parent
+	}
+
+	var c Change
+	for h.current != common {
+		undo, _ := h.Undo()
+		c.Actions = append(c.Actions, undo.Actions...)
+		c.Cursor = undo.Cursor
+	}
+	for i := len(down) - 1; i >= 0; i-- {
+		h.current.redo = down[i]
+		redo, _ := h.Redo()
+		c.Actions = append(c.Actions, redo.Actions...)
+		c.Cursor = redo.Cursor
+	}
+	return c, true
+}
+
+// Earlier moves to the state created just before the current one
+func (h *History) Earlier() (Change, bool) {
+	return h.Goto(h.current.Seq - 1)
+}
+
+// Later moves to the state created just after the current one
+func (h *History) Later() (Change, bool) {
+	return h.Goto(h.current.Seq + 1)
+}
+
+// invert returns the actions cancelling acts, in the order they must be applied
+func invert(acts []Action) []Action {
+	inv := make([]Action, 0, len(acts))
+	for i := len(acts) - 1; i >= 0; i-- {
+		inv = append(inv, acts[i].Invert())
+	}
+	return inv
+}
+
+// journal is the serialized form of a History
+type journal struct {
+	Current int            `json:"current"`
+	States  []journalState `json:"states"`
+}
+
+type journalState struct {
+	Parent int    `json:"parent"` // -1 for the root
+	Redo   int    `json:"redo"`   // -1 when there is nothing to redo
+	Change Change `json:"change"`
+}
+
+func (h *History) MarshalJSON() ([]byte, error) {
+	h.Commit()
+
+	j := journal{
+		Current: h.current.Seq,
+		States:  make([]journalState, 0, len(h.states)),
+	}
+	for _, s := range h.states {
+		js := journalState{Parent: -1, Redo: -1, Change: s.Change}
+		if s.parent != nil {
+			js.Parent = s.parent.Seq
+		}
+		if s.redo != nil {
+			js.Redo = s.redo.Seq
+		}
+		j.States = append(j.States, js)
+	}
+	return json.Marshal(j)
+}
+
+func (h *History) UnmarshalJSON(data []byte) error {
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if len(j.States) == 0 || j.Current < 0 || j.Current >= len(j.States) {
+		return errors.New("malformed undo journal")
+	}
+
+	states := make([]*State, len(j.States))
+	for i, js := range j.States {
+		states[i] = &State{Seq: i, Change: js.Change}
+		if i == 0 {
+			continue
+		}
+		// parents are always created before their children
+		if js.Parent < 0 || js.Parent >= i {
+			return errors.New("malformed undo journal")
+		}
+		states[i].parent = states[js.Parent]
+		states[js.Parent].children = append(states[js.Parent].children, states[i])
+	}
+	for i, js := range j.States {
+		if js.Redo > i && js.Redo < len(states) && states[js.Redo].parent == states[i] {
+			states[i].redo = states[js.Redo]
+		}
+	}
+
+	h.states = states
+	h.current = states[j.Current]
+	h.pending = Change{}
+	return nil
+}
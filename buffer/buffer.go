@@ -12,19 +12,129 @@ const (
 	TAB_SYMBOL = " "
 )
 
+// Buffer holds the text being edited. It is backed by a rope so inserting,
+// deleting and looking up lines cost O(log n) instead of O(file size)
+//
+// all the offsets and positions are expressed in runes, not bytes
 type Buffer struct {
-	Data string
+	root *node
 }
 
-func (b Buffer) SplitLines() []string {
-	return strings.Split(b.Data, "\n")
+// New creates a buffer holding s
+func New(s string) Buffer {
+	return Buffer{root: build(s)}
+}
+
+// String returns the whole content of the buffer
+func (b Buffer) String() string {
+	return b.Slice(0, b.Len())
+}
+
+// Len returns the number of runes in the buffer
+func (b Buffer) Len() int {
+	if b.root == nil {
+		return 0
+	}
+	return b.root.runes
 }
 
-// this function translates the internal buffer to something human-readable (\t
-// are multiple spaces, ...)
-func (b Buffer) Translate() Buffer {
-	tmp := strings.ReplaceAll(b.Data, "\t", strings.Repeat(TAB_SYMBOL, TAB_SIZE))
-	return Buffer{Data: tmp}
+// LineCount returns the number of lines in the buffer. An empty buffer still
+// has one (empty) line
+func (b Buffer) LineCount() int {
+	if b.root == nil {
+		return 1
+	}
+	return b.root.newlines + 1
+}
+
+// Line returns the content of line y, without the trailing newline
+func (b Buffer) Line(y int) string {
+	if y < 0 || y >= b.LineCount() {
+		return ""
+	}
+	start, end := b.lineBounds(y)
+	return b.Slice(start, end)
+}
+
+// LineLen returns the number of runes in line y
+func (b Buffer) LineLen(y int) int {
+	if y < 0 || y >= b.LineCount() {
+		return 0
+	}
+	start, end := b.lineBounds(y)
+	return end - start
+}
+
+func (b Buffer) lineBounds(y int) (start, end int) {
+	start = b.root.lineStart(y)
+	if y+1 < b.LineCount() {
+		end = b.root.lineStart(y+1) - 1
+	} else {
+		end = b.Len()
+	}
+	return start, end
+}
+
+// Offset converts a (x, y) position to a rune offset. The position is clamped
+// to the buffer boundaries
+func (b Buffer) Offset(x, y int) int {
+	if y < 0 {
+		return 0
+	}
+	if y >= b.LineCount() {
+		return b.Len()
+	}
+	start, end := b.lineBounds(y)
+	return start + max(0, min(x, end-start))
+}
+
+// Pos converts a rune offset to a (x, y) position
+func (b Buffer) Pos(off int) (x, y int) {
+	off = max(0, min(off, b.Len()))
+	y = b.root.newlinesBefore(off)
+	return off - b.root.lineStart(y), y
+}
+
+// RuneAt returns the rune at the given offset, or 0 when out of bounds
+func (b Buffer) RuneAt(off int) rune {
+	if off < 0 || off >= b.Len() {
+		return 0
+	}
+	return b.root.runeAt(off)
+}
+
+// Slice returns the runes between the start and end offsets
+func (b Buffer) Slice(start, end int) string {
+	var sb strings.Builder
+	b.root.appendRange(&sb, max(0, start), min(end, b.Len()))
+	return sb.String()
+}
+
+// Insert inserts s at the given offset
+func (b *Buffer) Insert(off int, s string) {
+	if s == "" {
+		return
+	}
+	l, r := split(b.root, off)
+	b.root = join(join(l, build(s)), r)
+}
+
+// Delete removes n runes starting at the given offset and returns them
+func (b *Buffer) Delete(off, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	l, rest := split(b.root, off)
+	deleted, r := split(rest, n)
+	b.root = join(l, r)
+
+	var sb strings.Builder
+	deleted.appendRange(&sb, 0, n)
+	return sb.String()
+}
+
+func (b Buffer) SplitLines() []string {
+	return strings.Split(b.String(), "\n")
 }
 
 // RuneLength returns the number of runes in a string
@@ -42,7 +152,7 @@ func TruncateToRunes(s string, n int) string {
 	if n <= 0 {
 		return ""
 	}
-	
+
 	runes := []rune(s)
 	if len(runes) <= n {
 		return s
@@ -0,0 +1,255 @@
+package buffer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLeaf is the maximum size in bytes of the text held by a single leaf.
+// Small leaves make edits cheap, big leaves keep the tree shallow
+const maxLeaf = 1024
+
+// node is a node of an immutable, height-balanced rope. Leaves hold the text,
+// inner nodes only hold the aggregated counts of their subtree so that rune
+// offsets and line numbers can be found in O(log n)
+//
+// nodes are never modified once created, so a rope can be shared freely
+// between copies of a Buffer
+type node struct {
+	left, right *node
+	text        string
+
+	height   int
+	bytes    int
+	runes    int
+	newlines int
+}
+
+func newLeaf(s string) *node {
+	return &node{
+		text:     s,
+		bytes:    len(s),
+		runes:    utf8.RuneCountInString(s),
+		newlines: strings.Count(s, "\n"),
+	}
+}
+
+func newNode(l, r *node) *node {
+	return &node{
+		left:     l,
+		right:    r,
+		height:   max(l.height, r.height) + 1,
+		bytes:    l.bytes + r.bytes,
+		runes:    l.runes + r.runes,
+		newlines: l.newlines + r.newlines,
+	}
+}
+
+func (n *node) isLeaf() bool {
+	return n.left == nil
+}
+
+func height(n *node) int {
+	if n == nil {
+		return -1
+	}
+	return n.height
+}
+
+// build creates a balanced rope out of s
+func build(s string) *node {
+	if s == "" {
+		return nil
+	}
+
+	// cut the string in chunks that do not split a rune in the middle
+	var leaves []*node
+	for len(s) > 0 {
+		end := min(len(s), maxLeaf)
+		for end < len(s) && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		leaves = append(leaves, newLeaf(s[:end]))
+		s = s[end:]
+	}
+
+	return buildFromLeaves(leaves)
+}
+
+func buildFromLeaves(leaves []*node) *node {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	mid := len(leaves) / 2
+	return newNode(buildFromLeaves(leaves[:mid]), buildFromLeaves(leaves[mid:]))
+}
+
+func rotateLeft(n *node) *node {
+	return newNode(newNode(n.left, n.right.left), n.right.right)
+}
+
+func rotateRight(n *node) *node {
+	return newNode(n.left.left, newNode(n.left.right, n.right))
+}
+
+// rebalance restores the AVL invariant of a node whose children heights
+// differ by at most 2
+func rebalance(n *node) *node {
+	switch bf := height(n.left) - height(n.right); {
+	case bf > 1:
+		if height(n.left.left) < height(n.left.right) {
+			n = newNode(rotateLeft(n.left), n.right)
+		}
+		return rotateRight(n)
+	case bf < -1:
+		if height(n.right.right) < height(n.right.left) {
+			n = newNode(n.left, rotateRight(n.right))
+		}
+		return rotateLeft(n)
+	}
+	return n
+}
+
+// join concatenates two ropes, keeping the result balanced
+func join(l, r *node) *node {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+
+	// merge small neighbours so that typing does not create a leaf per rune
+	if l.isLeaf() && r.isLeaf() && l.bytes+r.bytes <= maxLeaf {
+		return newLeaf(l.text + r.text)
+	}
+
+	switch {
+	case l.height > r.height+1:
+		return rebalance(newNode(l.left, join(l.right, r)))
+	case r.height > l.height+1:
+		return rebalance(newNode(join(l, r.left), r.right))
+	default:
+		return newNode(l, r)
+	}
+}
+
+// split cuts the rope in two, the left part holding the first i runes
+func split(n *node, i int) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+	if i <= 0 {
+		return nil, n
+	}
+	if i >= n.runes {
+		return n, nil
+	}
+
+	if n.isLeaf() {
+		b := byteOffset(n.text, i)
+		return newLeaf(n.text[:b]), newLeaf(n.text[b:])
+	}
+
+	if i < n.left.runes {
+		l, r := split(n.left, i)
+		return l, join(r, n.right)
+	}
+	l, r := split(n.right, i-n.left.runes)
+	return join(n.left, l), r
+}
+
+// lineStart returns the rune offset of the first rune of the given line
+func (n *node) lineStart(line int) int {
+	if n == nil || line <= 0 {
+		return 0
+	}
+	if line > n.newlines {
+		return n.runes
+	}
+
+	if n.isLeaf() {
+		off := 0
+		for _, r := range n.text {
+			off++
+			if r == '\n' {
+				line--
+				if line == 0 {
+					break
+				}
+			}
+		}
+		return off
+	}
+
+	if line <= n.left.newlines {
+		return n.left.lineStart(line)
+	}
+	return n.left.runes + n.right.lineStart(line-n.left.newlines)
+}
+
+// newlinesBefore returns the number of newlines in the first off runes
+func (n *node) newlinesBefore(off int) int {
+	if n == nil || off <= 0 {
+		return 0
+	}
+	if off >= n.runes {
+		return n.newlines
+	}
+
+	if n.isLeaf() {
+		return strings.Count(n.text[:byteOffset(n.text, off)], "\n")
+	}
+
+	if off <= n.left.runes {
+		return n.left.newlinesBefore(off)
+	}
+	return n.left.newlines + n.right.newlinesBefore(off-n.left.runes)
+}
+
+// appendRange writes the runes in [start, end) to sb
+func (n *node) appendRange(sb *strings.Builder, start, end int) {
+	if n == nil || start >= end || end <= 0 || start >= n.runes {
+		return
+	}
+
+	if n.isLeaf() {
+		if start <= 0 && end >= n.runes {
+			sb.WriteString(n.text)
+			return
+		}
+		sb.WriteString(n.text[byteOffset(n.text, start):byteOffset(n.text, end)])
+		return
+	}
+
+	n.left.appendRange(sb, start, end)
+	n.right.appendRange(sb, start-n.left.runes, end-n.left.runes)
+}
+
+// runeAt returns the rune at the given offset
+func (n *node) runeAt(off int) rune {
+	for !n.isLeaf() {
+		if off < n.left.runes {
+			n = n.left
+		} else {
+			off -= n.left.runes
+			n = n.right
+		}
+	}
+	r, _ := utf8.DecodeRuneInString(n.text[byteOffset(n.text, off):])
+	return r
+}
+
+// byteOffset converts a rune offset in s to a byte offset
+func byteOffset(s string, runes int) int {
+	if runes <= 0 {
+		return 0
+	}
+	for b := range s {
+		if runes == 0 {
+			return b
+		}
+		runes--
+	}
+	return len(s)
+}
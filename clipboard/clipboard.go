@@ -0,0 +1,146 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Provider gives access to the system clipboard
+type Provider interface {
+	Copy(text string) error
+	Paste() (string, error)
+}
+
+var ErrPasteUnsupported = errors.New("the clipboard cannot be read")
+
+// OSC52 copies with the OSC 52 escape sequence, which the terminal turns into
+// a copy to the system clipboard. It works over SSH, but the clipboard cannot
+// be read back
+type OSC52 struct {
+	Out  io.Writer // the terminal
+	Tmux bool      // wrap the sequence so tmux passes it to the terminal
+}
+
+func (o OSC52) Copy(text string) error {
+	if o.Out == nil {
+		return errors.New("no terminal to write to")
+	}
+
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if o.Tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err := io.WriteString(o.Out, seq)
+	return err
+}
+
+func (o OSC52) Paste() (string, error) {
+	return "", ErrPasteUnsupported
+}
+
+// Command copies and pastes by running external commands, which get the text
+// to copy on their standard input and give the pasted text on their standard
+// output
+type Command struct {
+	CopyArgs  []string
+	PasteArgs []string
+
+	// Command builds the commands to run. It is exec.Command unless a fake one
+	// is used in its place
+	Command func(name string, arg ...string) *exec.Cmd
+}
+
+func (c Command) Copy(text string) error {
+	_, err := c.run(c.CopyArgs, text, false)
+	return err
+}
+
+func (c Command) Paste() (string, error) {
+	return c.run(c.PasteArgs, "", true)
+}
+
+// run the command with input on its standard input, and return its standard
+// output when output is true
+func (c Command) run(args []string, input string, output bool) (string, error) {
+	command := c.Command
+	if command == nil {
+		command = exec.Command
+	}
+
+	// xclip and wl-copy leave a process running to own the selection, which
+	// keeps the outputs it inherits open. Run would wait for it if they were
+	// pipes, so the errors go to a file and the output of a copy is dropped
+	stderr, err := os.CreateTemp("", "tide-clipboard-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stderr = stderr
+	var stdout bytes.Buffer
+	if output {
+		cmd.Stdout = &stdout
+	}
+	if err := cmd.Run(); err != nil {
+		msg, _ := os.ReadFile(stderr.Name())
+		if msg := strings.TrimSpace(string(msg)); msg != "" {
+			return "", fmt.Errorf("%s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+func XClip() Command {
+	return Command{
+		CopyArgs:  []string{"xclip", "-selection", "clipboard", "-in"},
+		PasteArgs: []string{"xclip", "-selection", "clipboard", "-out"},
+	}
+}
+
+func XSel() Command {
+	return Command{
+		CopyArgs:  []string{"xsel", "--clipboard", "--input"},
+		PasteArgs: []string{"xsel", "--clipboard", "--output"},
+	}
+}
+
+func WlClipboard() Command {
+	return Command{
+		CopyArgs:  []string{"wl-copy"},
+		PasteArgs: []string{"wl-paste", "--no-newline"},
+	}
+}
+
+// lookPath finds the commands backends need
+var lookPath = exec.LookPath
+
+// Detect returns the backend fitting the session: wl-copy under Wayland,
+// xclip or xsel under X, and OSC 52 written to out otherwise
+func Detect(out io.Writer) Provider {
+	installed := func(c Command) bool {
+		_, err := lookPath(c.CopyArgs[0])
+		return err == nil
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" && installed(WlClipboard()) {
+		return WlClipboard()
+	}
+	if os.Getenv("DISPLAY") != "" {
+		for _, c := range []Command{XClip(), XSel()} {
+			if installed(c) {
+				return c
+			}
+		}
+	}
+	return OSC52{Out: out, Tmux: os.Getenv("TMUX") != ""}
+}
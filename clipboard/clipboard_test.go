@@ -0,0 +1,150 @@
+package clipboard
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fake replaces the command of c by a shell script, and records the commands
+// c asks for
+func fake(c Command, script string) (Command, *[][]string) {
+	var calls [][]string
+	c.Command = func(name string, arg ...string) *exec.Cmd {
+		calls = append(calls, append([]string{name}, arg...))
+		return exec.Command("sh", "-c", script)
+	}
+	return c, &calls
+}
+
+func TestCommandArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		backend     Command
+		copy, paste []string
+	}{
+		{"xclip", XClip(), []string{"xclip", "-selection", "clipboard", "-in"}, []string{"xclip", "-selection", "clipboard", "-out"}},
+		{"xsel", XSel(), []string{"xsel", "--clipboard", "--input"}, []string{"xsel", "--clipboard", "--output"}},
+		{"wl-clipboard", WlClipboard(), []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, calls := fake(tc.backend, "cat >/dev/null")
+			if err := c.Copy("text"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Paste(); err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{tc.copy, tc.paste}
+			if !slices.EqualFunc(*calls, want, slices.Equal) {
+				t.Errorf("got the commands %q, want %q", *calls, want)
+			}
+		})
+	}
+}
+
+func TestCommandCopy(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "copied")
+	c, _ := fake(XClip(), "cat >"+out)
+	if err := c.Copy("hello\nworld"); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(copied) != "hello\nworld" {
+		t.Errorf("copied %q, want %q", copied, "hello\nworld")
+	}
+}
+
+// the copy commands leave a process owning the selection behind, which must
+// not be waited for
+func TestCommandCopyDoesNotWait(t *testing.T) {
+	c, _ := fake(XClip(), "sleep 3 & cat >/dev/null")
+	start := time.Now()
+	if err := c.Copy("text"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("copy took %v, waiting for the process left behind", d)
+	}
+}
+
+func TestCommandPaste(t *testing.T) {
+	c, _ := fake(WlClipboard(), "printf 'pasted\\ntext'")
+	text, err := c.Paste()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "pasted\ntext" {
+		t.Errorf("pasted %q, want %q", text, "pasted\ntext")
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	c, _ := fake(XSel(), "echo 'cannot open display' >&2; exit 1")
+	if _, err := c.Paste(); err == nil || err.Error() != "xsel: cannot open display" {
+		t.Errorf("got the error %v, want the standard error of the command", err)
+	}
+
+	c, _ = fake(XSel(), "exit 2")
+	if err := c.Copy("text"); err == nil || err.Error() != "xsel: exit status 2" {
+		t.Errorf("got the error %v, want the exit status", err)
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tmux bool
+		want string
+	}{
+		{"raw", false, "\x1b]52;c;aGVsbG8=\a"},
+		{"tmux", true, "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\a\x1b\\"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := (OSC52{Out: &out, Tmux: tc.tmux}).Copy("hello"); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("wrote %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+
+	if _, err := (OSC52{}).Paste(); err != ErrPasteUnsupported {
+		t.Errorf("got the error %v, want %v", err, ErrPasteUnsupported)
+	}
+	if err := (OSC52{}).Copy("hello"); err == nil {
+		t.Error("copied without a terminal")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	defer func(f func(string) (string, error)) { lookPath = f }(lookPath)
+	lookPath = func(name string) (string, error) { return "/usr/bin/" + name, nil }
+
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	if p, ok := Detect(nil).(Command); !ok || p.CopyArgs[0] != "wl-copy" {
+		t.Errorf("got %#v under Wayland, want wl-copy", Detect(nil))
+	}
+
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DISPLAY", ":0")
+	if p, ok := Detect(nil).(Command); !ok || p.CopyArgs[0] != "xclip" {
+		t.Errorf("got %#v under X, want xclip", Detect(nil))
+	}
+
+	t.Setenv("DISPLAY", "")
+	t.Setenv("TMUX", "/tmp/tmux")
+	if p, ok := Detect(nil).(OSC52); !ok || !p.Tmux {
+		t.Errorf("got %#v without display, want OSC 52 wrapped for tmux", Detect(nil))
+	}
+}
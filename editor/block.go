@@ -0,0 +1,192 @@
+package editor
+
+import (
+	"strings"
+)
+
+// blockInsert is an insertion started on the first line of a block, to be
+// repeated on the other lines once the insert session is over
+type blockInsert struct {
+	first, last int  // lines of the block
+	col         int  // render column where the text is inserted
+	pad         bool // pad the lines too short to reach col with spaces
+	x, lineLen  int  // where the insertion began on the first line, and its length
+}
+
+// blockColumns returns the render columns of the selected block, the right one
+// being excluded
+func (w *Window) blockColumns() (left, right int) {
+	a, c := w.Selection.Anchor, w.InternalCursor
+	la := []rune(w.InternalBuffer.Line(a.Y))
+	lc := []rune(w.InternalBuffer.Line(c.Y))
+
+	ca, cc := renderColumn(la, a.X), renderColumn(lc, c.X)
+	left = min(ca, cc)
+	right = max(ca+widthAt(la, a.X, ca), cc+widthAt(lc, c.X, cc))
+	return left, right
+}
+
+// width of the x-th rune of a line drawn at the rx render column. The end of
+// the line is one column wide, like the cursor drawn there
+func widthAt(lineRunes []rune, x, rx int) int {
+	if x < len(lineRunes) {
+		return cellWidth(lineRunes[x], rx)
+	}
+	return 1
+}
+
+// columnsToRunes returns the runes of a line drawn, even partially, between
+// the left and right render columns, the end being excluded
+func columnsToRunes(lineRunes []rune, left, right int) (start, end int) {
+	start, end = len(lineRunes), len(lineRunes)
+	found := false
+	rx := 0
+	for i, r := range lineRunes {
+		if rx >= right {
+			end = i
+			break
+		}
+		cw := cellWidth(r, rx)
+		if !found && rx+cw > left {
+			start = i
+			found = true
+		}
+		rx += cw
+	}
+	if !found {
+		return len(lineRunes), len(lineRunes)
+	}
+	return start, end
+}
+
+// columnToRune returns the first rune of a line drawn at or after the rx
+// render column
+func columnToRune(lineRunes []rune, rx int) int {
+	col := 0
+	for i, r := range lineRunes {
+		if col >= rx {
+			return i
+		}
+		col += cellWidth(r, col)
+	}
+	return len(lineRunes)
+}
+
+// selectedBlockText returns the content of the block, one line per line of the
+// block
+func (w *Window) selectedBlockText() string {
+	first, last := w.selectedLines()
+	left, right := w.blockColumns()
+
+	lines := make([]string, 0, last-first+1)
+	for y := first; y <= last; y++ {
+		lineRunes := []rune(w.InternalBuffer.Line(y))
+		start, end := columnsToRunes(lineRunes, left, right)
+		lines = append(lines, string(lineRunes[start:end]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// delete the content of the block
+func (e *Editor) deleteBlock() {
+	first, last := e.selectedLines()
+	left, right := e.blockColumns()
+
+	for y := first; y <= last; y++ {
+		start, end := columnsToRunes([]rune(e.InternalBuffer.Line(y)), left, right)
+		e.deleteText(start, y, end-start)
+	}
+	e.cancelSelection()
+
+	e.InternalCursor.X = columnToRune([]rune(e.InternalBuffer.Line(first)), left)
+	e.InternalCursor.Y = first
+	e.moveInternalCursor(0, 0)
+}
+
+// paste the lines of text as a block whose top-left corner is at the rx render
+// column of line y. Lines are padded or added when they are too short
+func (e *Editor) pasteBlock(text string, rx, y int) {
+	for i, t := range strings.Split(text, "\n") {
+		ly := y + i
+		if ly >= e.InternalBuffer.LineCount() {
+			last := e.InternalBuffer.LineCount() - 1
+			e.insertText(e.InternalBuffer.LineLen(last), last, "\n")
+		}
+
+		lineRunes := []rune(e.InternalBuffer.Line(ly))
+		if width := renderColumn(lineRunes, len(lineRunes)); width < rx {
+			e.insertText(len(lineRunes), ly, strings.Repeat(" ", rx-width)+t)
+			continue
+		}
+		e.insertText(columnToRune(lineRunes, rx), ly, t)
+	}
+
+	e.InternalCursor.X = columnToRune([]rune(e.InternalBuffer.Line(y)), rx)
+	e.InternalCursor.Y = y
+	e.moveInternalCursor(0, 0)
+}
+
+// start inserting text on every line of the block, before it or after it
+func (e *Editor) startBlockInsert(after bool) {
+	first, last := e.selectedLines()
+	left, right := e.blockColumns()
+	e.cancelSelection()
+
+	col := left
+	if after {
+		col = right
+	}
+
+	// the first line may need to be padded to reach the column
+	lineRunes := []rune(e.InternalBuffer.Line(first))
+	if width := renderColumn(lineRunes, len(lineRunes)); after && width < col {
+		e.insertText(len(lineRunes), first, strings.Repeat(" ", col-width))
+		lineRunes = []rune(e.InternalBuffer.Line(first))
+	}
+
+	x := columnToRune(lineRunes, col)
+	e.blockInsert = &blockInsert{
+		first:   first,
+		last:    last,
+		col:     col,
+		pad:     after,
+		x:       x,
+		lineLen: len(lineRunes),
+	}
+
+	e.InternalCursor.X = x
+	e.InternalCursor.Y = first
+	e.updateRenderCursor()
+	e.SwitchMode()
+}
+
+// repeat the text inserted on the first line of the block on the other lines.
+// Only a plain insertion on a single line can be repeated
+func (e *Editor) finishBlockInsert() {
+	b := e.blockInsert
+	e.blockInsert = nil
+	if b == nil || e.InternalCursor.Y != b.first {
+		return
+	}
+
+	n := e.InternalBuffer.LineLen(b.first) - b.lineLen
+	if n <= 0 || e.InternalCursor.X != b.x+n {
+		return
+	}
+	text := e.InternalBuffer.Slice(e.InternalBuffer.Offset(b.x, b.first), e.InternalBuffer.Offset(b.x+n, b.first))
+
+	for y := b.first + 1; y <= b.last; y++ {
+		lineRunes := []rune(e.InternalBuffer.Line(y))
+		if width := renderColumn(lineRunes, len(lineRunes)); width < b.col {
+			// short lines are only extended when appending
+			if b.pad {
+				e.insertText(len(lineRunes), y, strings.Repeat(" ", b.col-width)+text)
+			}
+			continue
+		}
+		e.insertText(columnToRune(lineRunes, b.col), y, text)
+	}
+
+	e.InternalCursor.X = b.x
+	e.moveInternalCursor(0, 0)
+}
@@ -0,0 +1,174 @@
+package editor
+
+import (
+	"slices"
+	"unicode"
+
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/eze-kiel/tide/str"
+)
+
+// run f at every cursor of the window in turn, each one being the internal
+// cursor while f runs. The cursors f is not running at follow the text it
+// edits
+func (e *Editor) atEachCursor(f func()) {
+	if len(e.Cursors) == 0 {
+		f()
+		return
+	}
+
+	// the internal cursor goes last so the window scrolls to it in the end
+	e.Cursors = append(e.Cursors, e.InternalCursor)
+	for i := range e.Cursors {
+		e.InternalCursor = e.Cursors[i]
+		f()
+		e.Cursors[i] = e.InternalCursor
+	}
+
+	last := len(e.Cursors) - 1
+	e.InternalCursor = e.Cursors[last]
+	e.Cursors = e.Cursors[:last]
+	e.mergeCursors()
+	e.updateRenderCursor()
+}
+
+// remove the cursors that ended up at the same position
+func (e *Editor) mergeCursors() {
+	var cursors []cursor.Cursor
+	for _, c := range e.Cursors {
+		if c != e.InternalCursor && !slices.Contains(cursors, c) {
+			cursors = append(cursors, c)
+		}
+	}
+	e.Cursors = cursors
+}
+
+func (e *Editor) hasCursor(c cursor.Cursor) bool {
+	return c == e.InternalCursor || slices.Contains(e.Cursors, c)
+}
+
+// add a cursor at c, which becomes the internal cursor so the next cursor is
+// added from there
+func (e *Editor) addCursor(c cursor.Cursor) {
+	if e.hasCursor(c) {
+		return
+	}
+	e.Cursors = append(e.Cursors, e.InternalCursor)
+	e.InternalCursor = c
+	e.moveInternalCursor(0, 0)
+}
+
+// add a cursor on the line above (dy < 0) or below (dy > 0), at the same render
+// column
+func (e *Editor) addCursorVertically(dy int) {
+	y := e.InternalCursor.Y + dy
+	if y < 0 || y >= e.InternalBuffer.LineCount() {
+		return
+	}
+	rx, _ := e.internalToRenderPos(e.InternalCursor.X, e.InternalCursor.Y)
+	e.addCursor(cursor.Cursor{X: columnToRune([]rune(e.InternalBuffer.Line(y)), rx), Y: y})
+}
+
+// add a cursor on the next occurrence of the word under the cursor, at the same
+// place in the word. The search wraps around the end of the document
+func (e *Editor) addCursorOnNextWord() {
+	c := e.InternalCursor
+	lineRunes := []rune(e.InternalBuffer.Line(c.Y))
+	start, end := wordAt(lineRunes, c.X)
+	if start == end {
+		e.showError(str.NoWordUnderCursorErr)
+		return
+	}
+	word := lineRunes[start:end]
+
+	lines := e.InternalBuffer.LineCount()
+	for i := 0; i <= lines; i++ {
+		y := (c.Y + i) % lines
+		lineRunes := []rune(e.InternalBuffer.Line(y))
+
+		from := 0
+		if i == 0 {
+			from = end
+		}
+		for x := from; x+len(word) <= len(lineRunes); x++ {
+			if !isWholeWord(lineRunes, x, word) {
+				continue
+			}
+			next := cursor.Cursor{X: x + c.X - start, Y: y}
+			if !e.hasCursor(next) {
+				e.addCursor(next)
+				return
+			}
+		}
+	}
+
+	e.showWarning(str.NoOtherOccurrenceMsg)
+}
+
+// put a cursor on every selected line, at the beginning of the block for block
+// selections and at the end of the line otherwise
+func (e *Editor) addCursorsOnSelection() {
+	if !e.selecting() {
+		return
+	}
+	first, last := e.selectedLines()
+	left := -1
+	if e.Selection.Kind == BlockSelection {
+		left, _ = e.blockColumns()
+	}
+	e.cancelSelection()
+
+	y := e.InternalCursor.Y
+	e.Cursors = nil
+	for l := first; l <= last; l++ {
+		lineRunes := []rune(e.InternalBuffer.Line(l))
+		c := cursor.Cursor{X: len(lineRunes), Y: l}
+		if left >= 0 {
+			c.X = columnToRune(lineRunes, left)
+		}
+
+		if l == y {
+			e.InternalCursor = c
+		} else {
+			e.Cursors = append(e.Cursors, c)
+		}
+	}
+	e.moveInternalCursor(0, 0)
+}
+
+// go back to a single cursor
+func (e *Editor) clearCursors() {
+	e.Cursors = nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordAt returns the runes of the word x is in, the end being excluded. Both
+// are equal when there is no word at x
+func wordAt(lineRunes []rune, x int) (start, end int) {
+	if x >= len(lineRunes) || !isWordRune(lineRunes[x]) {
+		return x, x
+	}
+	start, end = x, x
+	for start > 0 && isWordRune(lineRunes[start-1]) {
+		start--
+	}
+	for end < len(lineRunes) && isWordRune(lineRunes[end]) {
+		end++
+	}
+	return start, end
+}
+
+// whether word is found at x in the line, and is not part of a longer word
+func isWholeWord(lineRunes []rune, x int, word []rune) bool {
+	if !slices.Equal(lineRunes[x:x+len(word)], word) {
+		return false
+	}
+	if x > 0 && isWordRune(lineRunes[x-1]) {
+		return false
+	}
+	end := x + len(word)
+	return end >= len(lineRunes) || !isWordRune(lineRunes[end])
+}
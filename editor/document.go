@@ -0,0 +1,149 @@
+package editor
+
+import (
+	"path/filepath"
+
+	"github.com/eze-kiel/tide/actions"
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/eze-kiel/tide/str"
+	"github.com/eze-kiel/tide/syntax"
+)
+
+// Document is a buffer opened in the editor. It holds everything that belongs
+// to a file: its content, its undo history, and where the cursor was left the
+// last time it was shown in a window
+type Document struct {
+	Filename string
+
+	OffsetX, OffsetY int
+
+	InternalBuffer buffer.Buffer
+	InternalCursor cursor.Cursor
+
+	History *actions.History // hold the internalBuffer changes, for the undo mechanism
+
+	lastSelection lineRange // the lines of the last selection, for the '< and '> addresses
+
+	highlighter     *syntax.Highlighter
+	highlighterName string // the file name the highlighter has been picked for
+
+	fileChanged bool
+	savedSeq    int // the state of the history the file was loaded or saved at
+}
+
+func newDocument(fname string) *Document {
+	return &Document{
+		Filename: fname,
+		History:  actions.NewHistory(),
+	}
+}
+
+// name of the document, as displayed to the user
+func (d *Document) name() string {
+	if d.Filename == "" {
+		return str.NoName
+	}
+	return d.Filename
+}
+
+// highlight returns the token classes of the runes of line y, or nil when
+// there is no grammar for the document. The grammar is picked again when the
+// file name changes
+func (d *Document) highlight(y int) []syntax.Class {
+	if d.highlighter == nil || d.highlighterName != d.Filename {
+		d.highlighter = syntax.NewHighlighter(syntax.Find(d.Filename, d.InternalBuffer.Line(0)))
+		d.highlighterName = d.Filename
+	}
+	return d.highlighter.Line(y, d.InternalBuffer.Line)
+}
+
+// pristine reports whether the document is the empty, unnamed one the editor
+// starts with, which can be replaced by the first file opened
+func (d *Document) pristine() bool {
+	return d.Filename == "" && !d.fileChanged && d.InternalBuffer.Len() == 0
+}
+
+// find the document holding fname, if it is already opened, even under
+// another path to the same file
+func (e *Editor) findDocument(fname string) *Document {
+	for _, d := range e.Documents {
+		if d.Filename != "" && samePath(d.Filename, fname) {
+			return d
+		}
+	}
+	return nil
+}
+
+// samePath reports whether the paths a and b lead to the same file
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// index of the current document in the buffer list
+func (e *Editor) documentIndex() int {
+	for i, d := range e.Documents {
+		if d == e.Document {
+			return i
+		}
+	}
+	return -1
+}
+
+// make d the document shown by the current window
+func (e *Editor) switchDocument(d *Document) {
+	e.History.Commit()
+	e.cancelSelection()
+	e.Window.save()
+	e.Window.load(d)
+	e.moveInternalCursor(0, 0)
+}
+
+// SwitchDocument makes the i-th document of the buffer list the current one
+func (e *Editor) SwitchDocument(i int) {
+	if i >= 0 && i < len(e.Documents) {
+		e.switchDocument(e.Documents[i])
+	}
+}
+
+// switch to the document n positions away in the buffer list
+func (e *Editor) cycleDocument(n int) {
+	i := e.documentIndex() + n
+	i = (i%len(e.Documents) + len(e.Documents)) % len(e.Documents)
+	e.switchDocument(e.Documents[i])
+}
+
+// close the current document, and show the next one in the list in every
+// window it was shown in
+func (e *Editor) closeDocument() {
+	closed := e.Document
+	i := e.documentIndex()
+	e.Documents = append(e.Documents[:i], e.Documents[i+1:]...)
+
+	if len(e.Documents) == 0 {
+		e.Documents = append(e.Documents, newDocument(""))
+	}
+	next := e.Documents[min(i, len(e.Documents)-1)]
+
+	for _, w := range e.layout.windows() {
+		if w.Document == closed && w != e.Window {
+			w.load(next)
+		}
+	}
+	e.switchDocument(next)
+}
+
+// modifiedDocument returns the first document with unsaved changes, if any
+func (e *Editor) modifiedDocument() *Document {
+	for _, d := range e.Documents {
+		if d.fileChanged {
+			return d
+		}
+	}
+	return nil
+}
@@ -2,17 +2,21 @@ package editor
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 
 	"github.com/eze-kiel/tide/actions"
 	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/clipboard"
 	"github.com/eze-kiel/tide/cursor"
 	"github.com/eze-kiel/tide/file"
 	"github.com/eze-kiel/tide/options"
+	"github.com/eze-kiel/tide/state"
+	"github.com/eze-kiel/tide/statusline"
 	"github.com/eze-kiel/tide/str"
+	"github.com/eze-kiel/tide/theme"
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
 )
@@ -21,46 +25,53 @@ const (
 	VisualMode = iota
 	EditMode
 	CommandMode
+	SearchMode
 
 	LineNumberWidth = 5
 )
 
-var DefaultMsgTimeout = 5
-
 type Editor struct {
 	sigs chan os.Signal
 
-	Mode     int
-	Screen   tcell.Screen
-	Filename string
-
-	Width, Height    int
-	OffsetX, OffsetY int
+	Mode   int
+	Screen tcell.Screen
 
-	InternalBuffer, RenderBuffer buffer.Buffer
-	InternalCursor, RenderCursor cursor.Cursor
+	Width, Height int
 
-	PreviousActions []actions.Action // hold the previous internalBuffer changes, for the undo mechanism
+	*Window               // the window having the focus
+	layout    *layout     // how the windows share the screen
+	Documents []*Document // every opened document, in the buffer list order
 
 	CommandBuffer    string
-	CommandCursorPos int
+	CommandCursorPos int // in runes
 
-	Selection struct {
-		Line         int
-		StartX, EndX int
-		Content      string
-	}
+	registers map[rune]register  // the named registers a to z, macros included
+	ring      []register         // the last yanked or deleted texts, the most recent first
+	register  rune               // the register selected with " for the next command
+	pasted    *pasted            // the last paste, to cycle through the ring
+	clipboard clipboard.Provider // the system clipboard, which is the + and * registers
+
+	blockInsert *blockInsert // the block insertion going on, if any
+
+	queue     []tcell.Event // events to handle before the ones of the screen
+	queueOnly bool          // whether the events only come from the queue, as for :normal
+
+	pending pending // the count, operator and motion command being typed
+	repeat  repeat  // the last change, made again with the . key
+
+	Events     EventSource // where the typed events come from
+	recording  *recording  // the macro being recorded, if any
+	lastMacro  rune        // the register of the last macro played, for @@
+	macroDepth int         // how many macros are being played in one another
 
-	Clipboard string
+	search    *search    // the last search, whose matches are highlighted
+	incSearch *incSearch // the search being typed, if any
 
-	StatusMsg     string
-	StatusTimeout int
-	fileChanged   bool
+	status   message   // the message on the status line
+	messages []message // the messages shown, for :messages
 
-	theme           string
-	backgroundColor tcell.Color
-	foregroundColor tcell.Color
-	highlightColor  tcell.Color
+	theme      *theme.Theme
+	statusLine statusline.Line // the segments of the status line
 
 	fastJumpLength   int  // how far you go when you hit D or U in VISU mode
 	autoSaveOnSwitch bool // auto save when going from EDIT to VISU modes
@@ -71,13 +82,22 @@ func New(o options.Opts) (*Editor, error) {
 		sigs:             make(chan os.Signal, 1),
 		Mode:             VisualMode,
 		autoSaveOnSwitch: o.AutoSaveOnSwitch,
-		fileChanged:      false,
-		theme:            o.Theme,
 	}
-
-	e.setTheme()
+	d := newDocument("")
+	e.Documents = []*Document{d}
+	e.Window = newWindow(d)
+	e.layout = &layout{window: e.Window}
 
 	var err error
+	e.theme, err = theme.Load(o.Theme)
+	if err != nil {
+		return nil, err
+	}
+	e.statusLine, err = statusline.Parse(o.StatusLine)
+	if err != nil {
+		return nil, err
+	}
+
 	e.Screen, err = tcell.NewScreen()
 	if err != nil {
 		return nil, err
@@ -86,6 +106,20 @@ func New(o options.Opts) (*Editor, error) {
 	if err = e.Screen.Init(); err != nil {
 		return nil, err
 	}
+	e.theme = e.theme.ForScreen(e.Screen)
+
+	e.Events = e.Screen
+
+	// OSC 52 sequences are written straight to the terminal
+	var tty io.Writer
+	if t, ok := e.Screen.Tty(); ok {
+		tty = t
+	}
+	e.clipboard = clipboard.Detect(tty)
+
+	e.Width, e.Height = e.Screen.Size()
+	e.relayout()
+	e.loadSession()
 
 	return e, nil
 }
@@ -104,168 +138,135 @@ func (e *Editor) Run() error {
 	e.Width, e.Height = e.Screen.Size()
 	e.fastJumpLength = (e.Height / 3)
 
+	// a step also ends on the events posted when a message expires, so the
+	// screen is drawn again without it
 	for {
-		e.Screen.Clear()
-		e.RenderBuffer = e.InternalBuffer.Translate()
-		lines := e.RenderBuffer.SplitLines()
-
-		// set the background color of the whole editor screen
-		for y := range e.Height {
-			for x := range e.Width {
-				e.Screen.SetContent(x, y, rune(0), nil, tcell.StyleDefault.
-					Background(e.backgroundColor))
-			}
-		}
-
-		for i := e.OffsetY; i < len(lines) && i < e.OffsetY+e.Height-2; i++ {
-			lineNumStr := fmt.Sprintf("%*d ", LineNumberWidth-1, i+1)
-			style := tcell.StyleDefault.
-				Background(e.backgroundColor).
-				Foreground(e.foregroundColor)
-
-			if i == e.InternalCursor.Y {
-				style = style.
-					Background(e.highlightColor).
-					Foreground(e.foregroundColor).
-					Bold(true)
-			}
-			for j, r := range lineNumStr {
-				if j < LineNumberWidth {
-					e.Screen.SetContent(j, i-e.OffsetY, r, nil, tcell.StyleDefault.
-						Background(e.backgroundColor).
-						Foreground(e.foregroundColor))
-				}
-			}
+		e.render()
+		e.step()
+	}
+}
 
-			for j, r := range lineNumStr {
-				if j < LineNumberWidth {
-					e.Screen.SetContent(j, i-e.OffsetY, r, nil, style)
-				}
-			}
+// render draws the whole editor on the screen
+func (e *Editor) render() {
+	e.Screen.Clear()
+	e.relayout()
 
-			l := lines[i]
-			lineRunes := []rune(l)
-			renderX := 0
-			for runeIdx := 0; runeIdx < len(lineRunes) && renderX < e.Width-LineNumberWidth; runeIdx++ {
-				r := lineRunes[runeIdx]
-				charWidth := 1
-				if r == '\t' {
-					// Handle tab expansion
-					tabPos := renderX % buffer.TAB_SIZE
-					charWidth = buffer.TAB_SIZE - tabPos
-					for k := 0; k < charWidth && renderX+k < e.Width-LineNumberWidth; k++ {
-						if renderX+k >= e.OffsetX {
-							e.Screen.SetContent(LineNumberWidth+(renderX+k-e.OffsetX), i-e.OffsetY, ' ', nil, tcell.StyleDefault.
-								Background(e.backgroundColor).
-								Foreground(e.foregroundColor))
-						}
-					}
-				} else {
-					// Handle regular characters including wide ones
-					charWidth = runewidth.RuneWidth(r)
-					if charWidth == 0 {
-						charWidth = 1 // control characters
-					}
-					if renderX >= e.OffsetX && renderX < e.OffsetX+e.Width-LineNumberWidth {
-						e.Screen.SetContent(LineNumberWidth+(renderX-e.OffsetX), i-e.OffsetY, r, nil, tcell.StyleDefault.
-							Background(e.backgroundColor).
-							Foreground(e.foregroundColor))
-					}
-					// For wide characters, fill additional columns with spaces
-					for k := 1; k < charWidth && renderX+k < e.Width-LineNumberWidth; k++ {
-						if renderX+k >= e.OffsetX {
-							e.Screen.SetContent(LineNumberWidth+(renderX+k-e.OffsetX), i-e.OffsetY, ' ', nil, tcell.StyleDefault.
-								Background(e.backgroundColor).
-								Foreground(e.foregroundColor))
-						}
-					}
-				}
-				renderX += charWidth
-			}
+	// set the background color of the whole editor screen
+	for y := range e.Height {
+		for x := range e.Width {
+			e.Screen.SetContent(x, y, rune(0), nil, e.theme.Text)
 		}
+	}
 
-		if e.Selection.Content != "" {
-			selectionRunes := []rune(e.Selection.Content)
-			renderX := e.Selection.StartX
-			for runeIdx := 0; runeIdx < len(selectionRunes) && renderX < e.Selection.EndX; runeIdx++ {
-				r := selectionRunes[runeIdx]
-				charWidth := runewidth.RuneWidth(r)
-				if charWidth == 0 {
-					charWidth = 1
-				}
-				if renderX >= e.OffsetX && renderX < e.OffsetX+e.Width-LineNumberWidth {
-					e.Screen.SetContent(LineNumberWidth+(renderX-e.OffsetX), e.Selection.Line-e.OffsetY, r, nil, tcell.StyleDefault.
-						Background(e.highlightColor).
-						Foreground(e.foregroundColor))
-				}
-				// For wide characters, fill additional columns
-				for k := 1; k < charWidth && renderX+k < e.Selection.EndX; k++ {
-					if renderX+k >= e.OffsetX && renderX+k < e.OffsetX+e.Width-LineNumberWidth {
-						e.Screen.SetContent(LineNumberWidth+(renderX+k-e.OffsetX), e.Selection.Line-e.OffsetY, ' ', nil, tcell.StyleDefault.
-							Background(e.highlightColor).
-							Foreground(e.foregroundColor))
-					}
-				}
-				renderX += charWidth
-			}
-		}
+	for _, w := range e.layout.windows() {
+		e.drawWindow(w)
+	}
+	e.drawSeparators(e.layout)
 
-		switch e.Mode {
-		case EditMode:
-			for i, r := range str.EditMode {
-				e.Screen.SetContent(i, e.Height-1, r, nil, tcell.StyleDefault.
-					Background(e.highlightColor).
-					Foreground(e.foregroundColor))
-			}
-		case VisualMode:
-			for i, r := range str.VisualMode {
-				e.Screen.SetContent(i, e.Height-1, r, nil, tcell.StyleDefault.
-					Background(e.highlightColor).
-					Foreground(e.foregroundColor))
-			}
-		case CommandMode:
-			for i := range e.Width {
-				e.Screen.SetContent(i, e.Height-1, rune(0), nil, tcell.StyleDefault.
-					Background(e.backgroundColor).
-					Foreground(e.foregroundColor))
-			}
-			e.Screen.SetContent(0, e.Height-1, ':', nil, tcell.StyleDefault.
-				Background(e.backgroundColor).
-				Foreground(e.foregroundColor))
-			for i, r := range e.CommandBuffer {
-				e.Screen.SetContent(i+1, e.Height-1, r, nil, tcell.StyleDefault.
-					Background(e.backgroundColor).
-					Foreground(e.foregroundColor))
-			}
+	switch e.Mode {
+	case EditMode, VisualMode:
+		e.drawStatusLine()
+	case CommandMode, SearchMode:
+		for i := range e.Width {
+			e.Screen.SetContent(i, e.Height-1, rune(0), nil, e.theme.CommandLine)
 		}
+		e.Screen.SetContent(0, e.Height-1, e.commandPrompt(), nil, e.theme.CommandLine)
+		e.drawStatusText(1, e.CommandBuffer, e.theme.CommandLine)
+		if m, ok := e.statusMessage(); ok {
+			e.drawStatusText(max(0, e.Width-runewidth.StringWidth(m.text)), m.text, e.messageStyle(m.severity))
+		}
+	}
 
-		if e.StatusMsg != "" && e.StatusTimeout > 0 {
-			e.StatusTimeout--
-			for i, r := range e.StatusMsg {
-				if i < e.Width {
-					e.Screen.SetContent(e.Width-len(e.StatusMsg)+i, e.Height-1, r, nil, tcell.StyleDefault.
-						Background(e.backgroundColor).
-						Foreground(e.foregroundColor))
-				}
-			}
+	if e.Mode == CommandMode || e.Mode == SearchMode {
+		line := []rune(e.CommandBuffer)
+		before := line[:min(max(e.CommandCursorPos, 0), len(line))]
+		e.Screen.ShowCursor(runewidth.StringWidth(string(before))+1, e.Height-1)
+	} else {
+		e.Screen.ShowCursor(e.area.x+LineNumberWidth+(e.RenderCursor.X-e.OffsetX), e.area.y+e.RenderCursor.Y-e.OffsetY)
+	}
+	e.Screen.Show()
+}
+
+// step waits for an event and handles it with the routine of the current mode
+func (e *Editor) step() {
+	e.dispatch()
+
+	// a whole insert session is a single change in the history, while any
+	// other command is a change on its own
+	if e.Mode != EditMode {
+		e.History.Commit()
+	}
+	e.endCommand()
+}
+
+// dispatch handles the next event with the routine of the current mode
+func (e *Editor) dispatch() {
+	switch e.Mode {
+	case EditMode:
+		e.editModeRoutine()
+	case VisualMode:
+		e.visualModeRoutine()
+	case CommandMode:
+		e.commandModeRoutine()
+	case SearchMode:
+		e.searchModeRoutine()
+	}
+
+	// a register selected with " only applies to the command that follows
+	if !e.pending.active() {
+		e.register = 0
+	}
+}
+
+// pollEvent returns the next event, the queued ones coming before the ones of
+// the screen
+func (e *Editor) pollEvent() tcell.Event {
+	if len(e.queue) > 0 {
+		ev := e.queue[0]
+		e.queue = e.queue[1:]
+		return ev
+	}
+	if e.queueOnly {
+		// cancel what waits for more keys than were queued
+		return tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone)
+	}
+
+	ev := e.Events.PollEvent()
+	e.recordKey(ev)
+	return ev
+}
+
+// pollKey returns the next key event, the other events, like the ones waking
+// the editor up when a message expires, being skipped
+func (e *Editor) pollKey() *tcell.EventKey {
+	for {
+		if ev, ok := e.pollEvent().(*tcell.EventKey); ok {
+			return ev
 		}
+	}
+}
 
-		if e.Mode == CommandMode {
-			e.Screen.ShowCursor(len(e.CommandBuffer)+1, e.Height-1)
-		} else {
-			e.Screen.ShowCursor(LineNumberWidth+(e.RenderCursor.X-e.OffsetX), e.RenderCursor.Y-e.OffsetY)
+// showLines displays lines at the bottom of the screen, over the document,
+// and waits for a key to be pressed before going back to the editor
+func (e *Editor) showLines(lines []string) {
+	lines = append(lines, str.PressAnyKeyMsg)
+	if len(lines) > e.Height {
+		lines = lines[len(lines)-e.Height:]
+	}
+
+	top := e.Height - len(lines)
+	for i, l := range lines {
+		for x := range e.Width {
+			e.Screen.SetContent(x, top+i, ' ', nil, e.theme.CommandLine)
 		}
-		e.Screen.Show()
-
-		switch e.Mode {
-		case EditMode:
-			e.editModeRoutine()
-		case VisualMode:
-			e.visualModeRoutine()
-		case CommandMode:
-			e.commandModeRoutine()
+		for x, r := range []rune(l) {
+			e.Screen.SetContent(x, top+i, r, nil, e.theme.CommandLine)
 		}
 	}
+	e.Screen.HideCursor()
+	e.Screen.Show()
+
+	e.pollKey()
 }
 
 // big brain time
@@ -296,54 +297,52 @@ func (e *Editor) internalToRenderPos(x, y int) (rx, ry int) {
 	ry = y
 
 	// for x we need to count expanded characters
-	lines := e.InternalBuffer.SplitLines()
-	if y < 0 || y >= len(lines) {
+	if y < 0 || y >= e.InternalBuffer.LineCount() {
 		return 0, y
 	}
 
 	// limit x to the boundaries based on rune count
-	lineRunes := []rune(lines[y])
+	lineRunes := []rune(e.InternalBuffer.Line(y))
 	if x < 0 {
 		x = 0
 	} else if x > len(lineRunes) {
 		x = len(lineRunes)
 	}
 
-	rx = 0
+	return renderColumn(lineRunes, x), ry
+}
+
+// renderColumn returns the render column where the x-th rune of a line is drawn
+func renderColumn(lineRunes []rune, x int) int {
+	rx := 0
 	for i := 0; i < x && i < len(lineRunes); i++ {
-		if lineRunes[i] == '\t' {
-			// align to the next tab stop
-			rx += buffer.TAB_SIZE - (rx % buffer.TAB_SIZE)
-		} else {
-			// account for wide characters
-			rx += runewidth.RuneWidth(lineRunes[i])
-		}
+		rx += cellWidth(lineRunes[i], rx)
 	}
-
-	return rx, ry
+	return rx
 }
 
-func (e *Editor) moveInternalCursor(dx, dy int) {
-	lines := e.InternalBuffer.SplitLines()
-
-	// empty buffer
-	if len(lines) == 0 {
-		e.InternalCursor.X = 0
-		e.InternalCursor.Y = 0
-		e.updateRenderCursor()
-		return
+// cellWidth returns the number of columns taken by r when drawn at the rx
+// render column
+func cellWidth(r rune, rx int) int {
+	if r == '\t' {
+		// align to the next tab stop
+		return buffer.TAB_SIZE - (rx % buffer.TAB_SIZE)
 	}
+	// account for wide characters, control characters still take a column
+	return max(1, runewidth.RuneWidth(r))
+}
 
+func (e *Editor) moveInternalCursor(dx, dy int) {
 	// compute new Y without going offlimits
 	newY := e.InternalCursor.Y + dy
 	if newY < 0 {
 		newY = 0
-	} else if newY >= len(lines) {
-		newY = len(lines) - 1
+	} else if newY >= e.InternalBuffer.LineCount() {
+		newY = e.InternalBuffer.LineCount() - 1
 	}
 
 	// compute new X without going offlimits (using rune count)
-	lineLength := buffer.RuneLength(lines[newY])
+	lineLength := e.InternalBuffer.LineLen(newY)
 	newX := e.InternalCursor.X + dx
 	if newX < 0 {
 		newX = 0
@@ -373,13 +372,13 @@ func (e *Editor) handleScrolling() {
 	if e.RenderCursor.Y < e.OffsetY {
 		e.OffsetY = e.RenderCursor.Y
 	}
-	if e.RenderCursor.Y >= e.OffsetY+e.Height-2 {
-		e.OffsetY = e.RenderCursor.Y - (e.Height - 3)
+	if e.RenderCursor.Y >= e.OffsetY+e.textHeight() {
+		e.OffsetY = e.RenderCursor.Y - (e.textHeight() - 1)
 	}
 
 	// adjust horizontal scrolling to account for line number width
-	if e.RenderCursor.X >= e.OffsetX+e.Width-LineNumberWidth {
-		e.OffsetX = e.RenderCursor.X - (e.Width - LineNumberWidth) + 1
+	if e.RenderCursor.X >= e.OffsetX+e.textWidth() {
+		e.OffsetX = e.RenderCursor.X - e.textWidth() + 1
 	}
 	if e.RenderCursor.X < e.OffsetX {
 		e.OffsetX = e.RenderCursor.X
@@ -388,37 +387,7 @@ func (e *Editor) handleScrolling() {
 
 // insert a character at the current cursor position
 func (e *Editor) insertRune(ch rune) {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		e.InternalBuffer.Data = "" + string(ch)
-		e.InternalCursor.X = 1
-		e.InternalCursor.Y = 0
-		e.updateRenderCursor()
-		return
-	}
-
-	y := e.InternalCursor.Y
-	x := e.InternalCursor.X
-	currentLineRunes := []rune(lines[y])
-
-	// Insert the rune at the correct position
-	newRunes := make([]rune, 0, len(currentLineRunes)+1)
-	newRunes = append(newRunes, currentLineRunes[:x]...)
-	newRunes = append(newRunes, ch)
-	newRunes = append(newRunes, currentLineRunes[x:]...)
-
-	e.PreviousActions = append(e.PreviousActions, actions.Action{
-		Kind:  actions.Kinds[actions.InsertRune],
-		Value: string(ch),
-		Pos: struct {
-			X int
-			Y int
-		}{x, y},
-	})
-
-	lines[y] = string(newRunes)
-	e.updateBufferFromLines(lines)
+	e.insertText(e.InternalCursor.X, e.InternalCursor.Y, string(ch))
 
 	e.InternalCursor.X++
 	e.updateRenderCursor()
@@ -426,47 +395,10 @@ func (e *Editor) insertRune(ch rune) {
 
 // insert a newline at the current cursor position
 func (e *Editor) insertNewlineAtCursor() {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		e.InternalBuffer.Data = "\n"
-		e.InternalCursor.X = 0
-		e.InternalCursor.Y = 1
-		e.updateRenderCursor()
-		return
-	}
-
 	y := e.InternalCursor.Y
 	x := e.InternalCursor.X
-	currentLineRunes := []rune(lines[y])
-
-	firstPart := ""
-	if x > 0 && x <= len(currentLineRunes) {
-		firstPart = string(currentLineRunes[:x])
-	}
-	secondPart := ""
-	if x < len(currentLineRunes) {
-		secondPart = string(currentLineRunes[x:])
-	}
-
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:y]...)
-	newLines = append(newLines, firstPart)
-	newLines = append(newLines, secondPart)
-	if y+1 < len(lines) {
-		newLines = append(newLines, lines[y+1:]...)
-	}
-
-	e.PreviousActions = append(e.PreviousActions, actions.Action{
-		Kind:  actions.Kinds[actions.InsertNL],
-		Value: "\n",
-		Pos: struct {
-			X int
-			Y int
-		}{x, y},
-	})
 
-	e.updateBufferFromLines(newLines)
+	e.insertText(x, y, "\n")
 
 	e.InternalCursor.X = 0
 	e.InternalCursor.Y = y + 1
@@ -475,24 +407,9 @@ func (e *Editor) insertNewlineAtCursor() {
 
 // insert a newline above the current cursor position
 func (e *Editor) insertNewlineAbove() {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		e.InternalBuffer.Data = "\n"
-		e.InternalCursor.X = 0
-		e.InternalCursor.Y = 1
-		e.updateRenderCursor()
-		return
-	}
-
 	y := e.InternalCursor.Y
 
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:y]...)
-	newLines = append(newLines, "")
-	newLines = append(newLines, lines[y:]...)
-
-	e.updateBufferFromLines(newLines)
+	e.insertText(0, y, "\n")
 
 	e.InternalCursor.X = 0
 	e.InternalCursor.Y = y
@@ -501,26 +418,9 @@ func (e *Editor) insertNewlineAbove() {
 
 // insert a newline under the current cursor position
 func (e *Editor) insertNewlineUnder() {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		e.InternalBuffer.Data = "\n"
-		e.InternalCursor.X = 0
-		e.InternalCursor.Y = 1
-		e.updateRenderCursor()
-		return
-	}
-
 	y := e.InternalCursor.Y
 
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:y+1]...)
-	newLines = append(newLines, "")
-	if y+1 < len(lines) {
-		newLines = append(newLines, lines[y+1:]...)
-	}
-
-	e.updateBufferFromLines(newLines)
+	e.insertText(e.InternalBuffer.LineLen(y), y, "\n")
 
 	e.InternalCursor.X = 0
 	e.InternalCursor.Y = y + 1
@@ -529,48 +429,28 @@ func (e *Editor) insertNewlineUnder() {
 
 // delete the character before the cursor (backspace)
 func (e *Editor) deleteRuneBeforeCursor() {
-	lines := e.InternalBuffer.SplitLines()
-
 	y := e.InternalCursor.Y
 	x := e.InternalCursor.X
 
-	// nothing to delete in empty buffer or at the beginning
-	if len(lines) == 0 || (y == 0 && x == 0) {
+	// nothing to delete at the beginning of the buffer
+	if y == 0 && x == 0 {
 		return
 	}
 
 	// if at beginning of line but not first line, join with previous line
 	if x == 0 && y > 0 {
-		prevLine := lines[y-1]
-		currentLine := lines[y]
-
-		newLine := prevLine + currentLine
-
-		newLines := make([]string, 0, len(lines)-1)
-		newLines = append(newLines, lines[:y-1]...)
-		newLines = append(newLines, newLine)
-		if y+1 < len(lines) {
-			newLines = append(newLines, lines[y+1:]...)
-		}
+		prevLen := e.InternalBuffer.LineLen(y - 1)
+		e.deleteText(prevLen, y-1, 1)
 
-		e.updateBufferFromLines(newLines)
-
-		e.InternalCursor.X = buffer.RuneLength(prevLine)
+		e.InternalCursor.X = prevLen
 		e.InternalCursor.Y = y - 1
 
 		e.updateRenderCursor()
 		return
 	}
 
-	currentLineRunes := []rune(lines[y])
-	if x > 0 && x <= len(currentLineRunes) {
-		newRunes := make([]rune, 0, len(currentLineRunes)-1)
-		newRunes = append(newRunes, currentLineRunes[:x-1]...)
-		newRunes = append(newRunes, currentLineRunes[x:]...)
-		lines[y] = string(newRunes)
-
-		e.updateBufferFromLines(lines)
-
+	if x > 0 && x <= e.InternalBuffer.LineLen(y) {
+		e.deleteText(x-1, y, 1)
 		e.InternalCursor.X--
 	}
 
@@ -579,203 +459,221 @@ func (e *Editor) deleteRuneBeforeCursor() {
 
 // delete the character at the cursor position (delete key)
 func (e *Editor) deleteRuneAtCursor() {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		return
-	}
-
-	y := e.InternalCursor.Y
-	x := e.InternalCursor.X
-	currentLineRunes := []rune(lines[y])
-
-	// if at end of line but not last line, join with next line
-	if x == len(currentLineRunes) && y < len(lines)-1 {
-		nextLine := lines[y+1]
-
-		newLine := lines[y] + nextLine
-
-		newLines := make([]string, 0, len(lines)-1)
-		newLines = append(newLines, lines[:y]...)
-		newLines = append(newLines, newLine)
-		if y+2 < len(lines) {
-			newLines = append(newLines, lines[y+2:]...)
-		}
-
-		e.updateBufferFromLines(newLines)
-	}
-
-	if x < len(currentLineRunes) {
-		newRunes := make([]rune, 0, len(currentLineRunes)-1)
-		newRunes = append(newRunes, currentLineRunes[:x]...)
-		newRunes = append(newRunes, currentLineRunes[x+1:]...)
-		lines[y] = string(newRunes)
-
-		e.updateBufferFromLines(lines)
-	}
-
-	e.updateRenderCursor()
+	e.deleteRuneAt(e.InternalCursor.X, e.InternalCursor.Y)
 }
 
 // delete the character at a given position
 func (e *Editor) deleteRuneAt(x, y int) {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 || y < 0 || y >= len(lines) {
+	if y < 0 || y >= e.InternalBuffer.LineCount() {
 		return
 	}
 
 	e.InternalCursor.X = x
 	e.InternalCursor.Y = y
-	currentLineRunes := []rune(lines[y])
-
-	// if at end of line but not last line, join with next line
-	if x == len(currentLineRunes) && y < len(lines)-1 {
-		nextLine := lines[y+1]
-
-		newLine := lines[y] + nextLine
-
-		newLines := make([]string, 0, len(lines)-1)
-		newLines = append(newLines, lines[:y]...)
-		newLines = append(newLines, newLine)
-		if y+2 < len(lines) {
-			newLines = append(newLines, lines[y+2:]...)
-		}
 
-		e.updateBufferFromLines(newLines)
+	// at the end of a line, this joins it with the next one
+	if x >= 0 && x <= e.InternalBuffer.LineLen(y) {
+		e.deleteText(x, y, 1)
 	}
 
-	if x >= 0 && x < len(currentLineRunes) {
-		newRunes := make([]rune, 0, len(currentLineRunes)-1)
-		newRunes = append(newRunes, currentLineRunes[:x]...)
-		newRunes = append(newRunes, currentLineRunes[x+1:]...)
-		lines[y] = string(newRunes)
+	e.updateRenderCursor()
+}
 
-		e.updateBufferFromLines(lines)
+// insert s in the internal buffer at the (x, y) position
+// every change of the buffer goes through insertText and deleteText so it
+// ends up in the history
+func (e *Editor) insertText(x, y int, s string) {
+	if s == "" {
+		return
 	}
+	off := e.InternalBuffer.Offset(x, y)
+	x, y = e.InternalBuffer.Pos(off)
 
-	e.updateRenderCursor()
+	e.bufferInsert(off, s)
+	e.History.Record(actions.Action{
+		Kind:  actions.Kinds[actions.Insert],
+		Value: s,
+		Pos:   actions.Pos{X: x, Y: y},
+	}, e.cursorPos())
+	e.fileChanged = true
+	e.repeat.changed = true
 }
 
-// helper function to update the internal buffer from an array of lines
-func (e *Editor) updateBufferFromLines(lines []string) {
+// delete n runes from the internal buffer starting at the (x, y) position,
+// and return what has been deleted
+func (e *Editor) deleteText(x, y, n int) string {
+	off := e.InternalBuffer.Offset(x, y)
+	x, y = e.InternalBuffer.Pos(off)
+
+	deleted := e.bufferDelete(off, n)
+	if deleted == "" {
+		return ""
+	}
+	e.History.Record(actions.Action{
+		Kind:  actions.Kinds[actions.Delete],
+		Value: deleted,
+		Pos:   actions.Pos{X: x, Y: y},
+	}, e.cursorPos())
 	e.fileChanged = true
-	e.InternalBuffer.Data = strings.Join(lines, "\n")
+	e.repeat.changed = true
+	return deleted
 }
 
-// save internal buffer to file
-func (e *Editor) SaveToFile() {
-	if err := file.Write(e.Filename, e.InternalBuffer.Data); err != nil {
-		e.StatusMsg = "Error: " + err.Error()
-	} else {
-		if e.autoSaveOnSwitch {
-			e.StatusMsg = str.AutoSavedMsg + e.Filename
-		} else {
-			e.StatusMsg = str.SavedMsg + e.Filename
-		}
-	}
-	e.StatusTimeout = DefaultMsgTimeout
+// insert s at the off offset of the internal buffer. The other windows showing
+// the document keep their cursor on the same text
+func (e *Editor) bufferInsert(off int, s string) {
+	e.editBuffer(off, buffer.RuneLength(s), func() {
+		e.InternalBuffer.Insert(off, s)
+	})
 }
 
-func (e *Editor) replaceRuneUnder() {
-	ev := e.Screen.PollEvent()
-	switch ev := ev.(type) {
-	case *tcell.EventKey:
-		switch ev.Key() {
-		case tcell.KeyRune:
-			e.deleteRuneAtCursor()
-			e.insertRune(ev.Rune())
-		}
-	}
+// delete n runes at the off offset of the internal buffer. The other windows
+// showing the document keep their cursor on the same text
+func (e *Editor) bufferDelete(off, n int) string {
+	var deleted string
+	e.editBuffer(off, -n, func() {
+		deleted = e.InternalBuffer.Delete(off, n)
+	})
+	return deleted
 }
 
-func (e *Editor) selectLine() {
-	lines := e.InternalBuffer.SplitLines()
+// run edit, which inserts (delta > 0) or deletes (delta < 0) runes at off, and
+// move the cursors of the other windows, the other cursors of the window and
+// the selection anchors accordingly
+func (e *Editor) editBuffer(off, delta int, edit func()) {
+	var positions []*cursor.Cursor
+	for _, w := range e.layout.windows() {
+		if w.Document != e.Document {
+			continue
+		}
+		if w != e.Window {
+			positions = append(positions, &w.InternalCursor)
+		}
+		for i := range w.Cursors {
+			positions = append(positions, &w.Cursors[i])
+		}
+		if w.selecting() {
+			positions = append(positions, &w.Selection.Anchor)
+		}
+	}
 
-	if len(lines) == 0 {
-		return
+	offsets := make([]int, len(positions))
+	for i, p := range positions {
+		offsets[i] = e.InternalBuffer.Offset(p.X, p.Y)
 	}
 
-	y := e.InternalCursor.Y
-	// Use the rendered version for display, but track the actual line content
-	renderLine := strings.ReplaceAll(lines[y], "\t", strings.Repeat(buffer.TAB_SYMBOL, buffer.TAB_SIZE))
-	e.Selection.Content = renderLine
-	e.Selection.StartX = 0
-	e.Selection.EndX = buffer.DisplayWidth(renderLine)
-	e.Selection.Line = y
-}
+	// the lines from the edited one on are highlighted again
+	if e.highlighter != nil {
+		_, y := e.InternalBuffer.Pos(off)
+		e.highlighter.Invalidate(y)
+	}
 
-func (e *Editor) cancelSelection() {
-	lines := e.InternalBuffer.SplitLines()
+	edit()
 
-	if len(lines) == 0 {
-		return
+	for i, p := range positions {
+		o := offsets[i]
+		if o > off || (delta > 0 && o == off) {
+			o = max(off, o+delta)
+		}
+		p.X, p.Y = e.InternalBuffer.Pos(o)
 	}
-
-	e.Selection = struct {
-		Line    int
-		StartX  int
-		EndX    int
-		Content string
-	}{}
 }
 
-func (e *Editor) copySelection() {
-	e.Clipboard = e.Selection.Content
+// the position of the internal cursor, as stored in the history
+func (e *Editor) cursorPos() actions.Pos {
+	return actions.Pos{X: e.InternalCursor.X, Y: e.InternalCursor.Y}
 }
 
-func (e *Editor) deleteSelection() {
-	if e.Selection.Content == "" {
-		return
+// apply a change coming from the history to the internal buffer, without
+// recording it again. The cursor goes back to where the change began
+func (e *Editor) applyChange(c actions.Change) {
+	for _, a := range c.Actions {
+		off := e.InternalBuffer.Offset(a.Pos.X, a.Pos.Y)
+		switch a.Kind {
+		case actions.Kinds[actions.Insert]:
+			e.bufferInsert(off, a.Value)
+		case actions.Kinds[actions.Delete]:
+			e.bufferDelete(off, buffer.RuneLength(a.Value))
+		}
 	}
+	e.InternalCursor.X = c.Cursor.X
+	e.InternalCursor.Y = c.Cursor.Y
+	// undoing up to the saved text leaves the file unchanged
+	e.fileChanged = e.History.Current() != e.savedSeq
+	e.moveInternalCursor(0, 0)
+}
 
-	lines := e.InternalBuffer.SplitLines()
-	y := e.Selection.Line
-
-	if y < 0 || y >= len(lines) {
-		return
+// Open loads fname in a new document, along with its undo history if it has
+// been persisted, and switches to it. A file that does not exist yet is not an
+// error
+func (e *Editor) Open(fname string) error {
+	if d := e.findDocument(fname); d != nil {
+		e.switchDocument(d)
+		return nil
 	}
 
-	startX := e.renderToInternalX(e.Selection.StartX, y)
-	endX := e.renderToInternalX(e.Selection.EndX, y)
-
-	currentLine := lines[y]
+	d := newDocument(fname)
+	if file.Exists(fname) {
+		data, err := file.Read(fname)
+		if err != nil {
+			return err
+		}
+		d.InternalBuffer = buffer.New(data)
 
-	if startX < 0 {
-		startX = 0
-	}
-	if startX > len(currentLine) {
-		startX = len(currentLine)
+		h, err := state.LoadUndo(fname, data)
+		if err != nil {
+			e.showError(str.CannotLoadUndoErr + err.Error())
+		}
+		if h != nil {
+			d.History = h
+		}
+		d.savedSeq = d.History.Current()
 	}
 
-	if endX < 0 {
-		endX = 0
-	}
-	if endX > len(currentLine) {
-		endX = len(currentLine)
+	// the empty document the editor starts with is not worth keeping
+	if i := e.documentIndex(); i >= 0 && e.Document.pristine() {
+		e.Documents[i] = d
+	} else {
+		e.Documents = append(e.Documents, d)
 	}
+	e.switchDocument(d)
+	return nil
+}
 
-	if startX >= endX {
+// save internal buffer to file
+func (e *Editor) SaveToFile() {
+	// the saved text is a state of the history, which undo can go back to
+	e.History.Commit()
+
+	content := e.InternalBuffer.String()
+	if err := file.Write(e.Filename, content); err != nil {
+		e.showError("Error: " + err.Error())
 		return
 	}
+	e.fileChanged, e.savedSeq = false, e.History.Current()
+	if err := state.SaveUndo(e.Filename, content, e.History); err != nil {
+		e.showError(str.CannotSaveUndoErr + err.Error())
+	} else if e.autoSaveOnSwitch {
+		e.showInfo(str.AutoSavedMsg + e.Filename)
+	} else {
+		e.showInfo(str.SavedMsg + e.Filename)
+	}
+}
 
-	newLine := currentLine[:startX] + currentLine[endX:]
-	lines[y] = newLine
-	e.updateBufferFromLines(lines)
-
-	e.InternalCursor.X = startX
-	e.InternalCursor.Y = y
-	e.updateRenderCursor()
-
-	e.Selection.Content = ""
+func (e *Editor) replaceRuneUnder() {
+	ev := e.pollKey()
+	switch ev.Key() {
+	case tcell.KeyRune:
+		e.deleteRuneAtCursor()
+		e.insertRune(ev.Rune())
+	}
 }
 
 func (e *Editor) renderToInternalX(renderX, y int) int {
-	lines := e.InternalBuffer.SplitLines()
-	if y < 0 || y >= len(lines) {
+	if y < 0 || y >= e.InternalBuffer.LineCount() {
 		return -1
 	}
-	lineRunes := []rune(lines[y])
+	lineRunes := []rune(e.InternalBuffer.Line(y))
 
 	internalX := 0
 	renderCol := 0
@@ -800,90 +698,44 @@ func (e *Editor) renderToInternalX(renderX, y int) int {
 	return internalX
 }
 
-func (e *Editor) pasteUnder() {
-	if e.Clipboard == "" {
+func (e *Editor) undo() {
+	c, ok := e.History.Undo()
+	if !ok {
+		e.showWarning(str.NoMoreUndoMsg)
 		return
 	}
+	e.applyChange(c)
+}
 
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		e.InternalBuffer.Data = e.Clipboard
-		e.InternalCursor.X = 0
-		e.InternalCursor.Y = 1
-		e.updateRenderCursor()
+func (e *Editor) redo() {
+	c, ok := e.History.Redo()
+	if !ok {
+		e.showWarning(str.NoMoreRedoMsg)
 		return
 	}
-
-	y := e.InternalCursor.Y
-
-	newLines := make([]string, 0, len(lines)+1)
-	newLines = append(newLines, lines[:y+1]...)
-	newLines = append(newLines, e.Clipboard)
-	if y+1 < len(lines) {
-		newLines = append(newLines, lines[y+1:]...)
-	}
-	e.cancelSelection()
-	e.updateBufferFromLines(newLines)
-
-	e.InternalCursor.X = 0
-	e.InternalCursor.Y = y + 1
-	e.updateRenderCursor()
+	e.applyChange(c)
 }
 
-func (e *Editor) toggleCommentLine() {
-	lines := e.InternalBuffer.SplitLines()
-
-	if len(lines) == 0 {
-		e.InternalCursor.X = 0
-		e.InternalCursor.Y = 1
-		e.updateRenderCursor()
+// go back to the state created before the current one, even if it lives on
+// another branch of the undo tree
+func (e *Editor) earlier() {
+	c, ok := e.History.Earlier()
+	if !ok {
+		e.showWarning(str.NoMoreUndoMsg)
 		return
 	}
-
-	y := e.InternalCursor.Y
-
-	// todo: use specific comment based on the file extension if known,
-	// otherwise go to default
-
-	newLines := make([]string, 0, len(lines)+1)
-	parts := strings.Fields(lines[y])
-	if len(parts) == 0 || !strings.Contains(parts[0], str.Comment) {
-		// comment the line
-		newLines = append(newLines, lines[:y]...)
-		newLines = append(newLines, strings.Join([]string{str.Comment, lines[y]}, " "))
-		if y+1 < len(lines) {
-			newLines = append(newLines, lines[y+1:]...)
-		}
-	} else {
-		// uncomment the line
-		newLines = append(newLines, lines[:y]...)
-		newLines = append(newLines, strings.Replace(lines[y], str.Comment+" ", "", 1))
-		if y+1 < len(lines) {
-			newLines = append(newLines, lines[y+1:]...)
-		}
-	}
-
-	e.updateBufferFromLines(newLines)
-
-	e.InternalCursor.X = len(lines[y]) + len(str.Comment)
-	e.InternalCursor.Y = y
-	e.updateRenderCursor()
+	e.applyChange(c)
+	e.showInfo(fmt.Sprintf(str.HistoryStateMsg, e.History.Current(), e.History.Last()))
 }
 
-func (e *Editor) undo() {
-	if len(e.PreviousActions) == 0 {
-		e.StatusMsg = str.NoMoreUndoMsg
-		e.StatusTimeout = DefaultMsgTimeout
+// go to the state created after the current one, even if it lives on another
+// branch of the undo tree
+func (e *Editor) later() {
+	c, ok := e.History.Later()
+	if !ok {
+		e.showWarning(str.NoMoreRedoMsg)
 		return
 	}
-
-	// save the latest action, then drop it
-	prev := e.PreviousActions[len(e.PreviousActions)-1]
-	e.PreviousActions = e.PreviousActions[:len(e.PreviousActions)-1]
-
-	switch prev.Kind {
-	case actions.Kinds[actions.InsertRune], actions.Kinds[actions.InsertNL]:
-		e.deleteRuneAt(prev.Pos.X, prev.Pos.Y)
-	}
+	e.applyChange(c)
+	e.showInfo(fmt.Sprintf(str.HistoryStateMsg, e.History.Current(), e.History.Last()))
 }
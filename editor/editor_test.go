@@ -0,0 +1,124 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/str"
+	"github.com/eze-kiel/tide/theme"
+	"github.com/gdamore/tcell/v2"
+)
+
+// typedKeys gives the editor the keys typed by a test, which fails if the
+// editor waits for more
+type typedKeys struct {
+	t      *testing.T
+	events []tcell.Event
+}
+
+func (k *typedKeys) PollEvent() tcell.Event {
+	if len(k.events) == 0 {
+		k.t.Fatal("waiting for a key that was not typed")
+	}
+	ev := k.events[0]
+	k.events = k.events[1:]
+	return ev
+}
+
+// newTestEditor returns an editor showing text on a simulated screen
+func newTestEditor(t *testing.T, text string) *Editor {
+	t.Helper()
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Fini)
+	s.SetSize(80, 24)
+
+	e := &Editor{Mode: VisualMode, Screen: s, Events: &typedKeys{t: t}}
+	e.theme, _ = theme.Load("dark")
+	d := newDocument("")
+	e.Documents = []*Document{d}
+	e.Window = newWindow(d)
+	e.layout = &layout{window: e.Window}
+	e.Width, e.Height = s.Size()
+	e.relayout()
+	e.InternalBuffer = buffer.New(text)
+	return e
+}
+
+// typeKeys has the editor handle the keys of s, Esc being written \x1b and
+// Enter \n, drawing the screen before each of them
+func typeKeys(e *Editor, s string) {
+	keys := e.Events.(*typedKeys)
+	for _, r := range s {
+		ev := tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+		switch r {
+		case '\x1b':
+			ev = tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone)
+		case '\n':
+			ev = tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+		}
+		keys.events = append(keys.events, ev)
+	}
+	for len(keys.events) > 0 {
+		e.render()
+		e.step()
+	}
+}
+
+func TestCommandLineAfterEsc(t *testing.T) {
+	e := newTestEditor(t, "one\ntwo\nthree\n")
+
+	// the cursor of the cancelled command line must not be kept
+	typeKeys(e, ":abcdef\x1b:")
+	if e.CommandCursorPos != 0 {
+		t.Errorf("the cursor is at %d on an empty command line", e.CommandCursorPos)
+	}
+	typeKeys(e, "2\n")
+	if e.InternalCursor.Y != 1 {
+		t.Errorf("the cursor is on line %d, want 2", e.InternalCursor.Y+1)
+	}
+}
+
+func TestRepeatCount(t *testing.T) {
+	for _, tc := range []struct {
+		name, keys, want string
+	}{
+		{"without count", "dd.", "3\n4\n5\n6\n7\n8\n"},
+		{"count replacing the one of the change", "2dd3.", "6\n7\n8\n"},
+		{"count after the operator", "d2d3.", "6\n7\n8\n"},
+		{"change taking no count", "ix\x1b3.", "xxxx1\n2\n3\n4\n5\n6\n7\n8\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEditor(t, "1\n2\n3\n4\n5\n6\n7\n8\n")
+			typeKeys(e, tc.keys)
+			if got := e.InternalBuffer.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRangeLineZero(t *testing.T) {
+	for _, tc := range []struct {
+		name, keys, want string
+		invalid          bool
+	}{
+		{"line 0", ":0,2d\n", "three\n", false},
+		{"line 0 alone", ":0d\n", "two\nthree\n", false},
+		{"offset before the first line", ":.-1d\n", "one\ntwo\nthree\n", true},
+		{"range ending before the first line", ":1,.-1d\n", "one\ntwo\nthree\n", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEditor(t, "one\ntwo\nthree\n")
+			typeKeys(e, tc.keys)
+			if got := e.InternalBuffer.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if invalid := e.status.text == str.InvalidRangeErr; invalid != tc.invalid {
+				t.Errorf("got the message %q, want the invalid range error: %v", e.status.text, tc.invalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package editor
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/str"
+	"github.com/gdamore/tcell/v2"
+)
+
+// splitCommand splits cmd, without its range, into the command name and its
+// arguments. The name is made of letters, possibly followed by a '!'
+func splitCommand(cmd string) (name, args string) {
+	n := strings.IndexFunc(cmd, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if n < 0 {
+		return cmd, ""
+	}
+	if n > 0 && cmd[n] == '!' {
+		n++
+	}
+	return cmd[:n], strings.TrimSpace(cmd[n:])
+}
+
+// linesText returns the content of the lines of r, without the last newline
+func (e *Editor) linesText(r lineRange) string {
+	b := e.InternalBuffer
+	return b.Slice(b.Offset(0, r.first), b.Offset(b.LineLen(r.last), r.last))
+}
+
+// remove the lines of r from the document, along with one of the newlines
+// around them
+func (e *Editor) removeLines(r lineRange) {
+	b := e.InternalBuffer
+	start, end := b.Offset(0, r.first), b.Offset(b.LineLen(r.last), r.last)
+	if r.last+1 < b.LineCount() {
+		end++
+	} else if r.first > 0 {
+		start--
+	}
+
+	x, y := b.Pos(start)
+	e.deleteText(x, y, end-start)
+}
+
+// insert lines of text under line y, or above the first line when y is -1
+func (e *Editor) insertLinesUnder(y int, text string) {
+	if y < 0 {
+		e.insertText(0, 0, text+"\n")
+		return
+	}
+	e.insertText(e.InternalBuffer.LineLen(y), y, "\n"+text)
+}
+
+// put the cursor at the beginning of line y
+func (e *Editor) goToLine(y int) {
+	e.InternalCursor.X = 0
+	e.InternalCursor.Y = y
+	e.moveInternalCursor(0, 0)
+}
+
+// :d, delete the lines and put them in the registers
+func (e *Editor) deleteLines(r lineRange) {
+	e.yankLines(r)
+	e.removeLines(r)
+	e.goToLine(min(r.first, e.InternalBuffer.LineCount()-1))
+}
+
+// :y, put the lines in the registers
+func (e *Editor) yankLines(r lineRange) {
+	e.yank(e.linesText(r), LineSelection)
+}
+
+// parseDestination reads the address of the line the lines are moved or
+// copied under, which can be 0 to put them at the top
+func (e *Editor) parseDestination(args string) (int, error) {
+	y, rest, ok, err := e.parseAddress(args)
+	if err != nil {
+		return 0, err
+	}
+	if !ok || strings.TrimSpace(rest) != "" || y < -1 || y >= e.InternalBuffer.LineCount() {
+		return 0, errors.New(str.InvalidAddressErr)
+	}
+	return y, nil
+}
+
+// :m, move the lines under line dest
+func (e *Editor) moveLines(r lineRange, dest int) error {
+	if dest >= r.first && dest < r.last {
+		return errors.New(str.MoveIntoItselfErr)
+	}
+	if dest == r.last || dest == r.first-1 {
+		e.goToLine(r.last)
+		return nil
+	}
+
+	text := e.linesText(r)
+	e.removeLines(r)
+	n := r.last - r.first + 1
+	if dest > r.last {
+		dest -= n
+	}
+	e.insertLinesUnder(dest, text)
+	e.goToLine(dest + n)
+	return nil
+}
+
+// :t, copy the lines under line dest
+func (e *Editor) copyLines(r lineRange, dest int) {
+	n := r.last - r.first + 1
+	e.insertLinesUnder(dest, e.linesText(r))
+	e.goToLine(dest + n)
+}
+
+// :j, join the lines of r, or the line and the next one when r is a single
+// line. The indentation of the joined lines is replaced by a space
+func (e *Editor) joinLines(r lineRange) {
+	if r.first == r.last {
+		r.last++
+	}
+	if r.last >= e.InternalBuffer.LineCount() {
+		return
+	}
+
+	x := 0
+	for range r.last - r.first {
+		line := e.InternalBuffer.Line(r.first)
+		next := e.InternalBuffer.Line(r.first + 1)
+		trimmed := strings.TrimLeft(next, " \t")
+
+		x = buffer.RuneLength(line)
+		e.deleteText(x, r.first, 1+buffer.RuneLength(next)-buffer.RuneLength(trimmed))
+		if line != "" && trimmed != "" && !strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\t") {
+			e.insertText(x, r.first, " ")
+		}
+	}
+
+	e.InternalCursor.X = x
+	e.InternalCursor.Y = r.first
+	e.moveInternalCursor(0, 0)
+}
+
+var numberRegexp = regexp.MustCompile(`-?\d+`)
+
+// :sort, sort the lines. reverse sorts them in the reverse order, and the
+// options are i to ignore the case, n to sort by the first number of the lines
+// and u to only keep the first of identical lines
+func (e *Editor) sortLines(r lineRange, reverse bool, options string) error {
+	var ignoreCase, numeric, unique bool
+	for _, o := range options {
+		switch o {
+		case 'i':
+			ignoreCase = true
+		case 'n':
+			numeric = true
+		case 'u':
+			unique = true
+		case ' ':
+		default:
+			return errors.New(str.InvalidArgumentErr + string(o))
+		}
+	}
+
+	key := func(s string) string {
+		if ignoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	number := func(s string) int {
+		n, _ := strconv.Atoi(numberRegexp.FindString(s))
+		return n
+	}
+	compare := func(a, b string) int {
+		if numeric {
+			return number(a) - number(b)
+		}
+		return strings.Compare(key(a), key(b))
+	}
+
+	old := e.linesText(r)
+	lines := strings.Split(old, "\n")
+	slices.SortStableFunc(lines, func(a, b string) int {
+		if reverse {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+	if unique {
+		lines = slices.CompactFunc(lines, func(a, b string) bool { return compare(a, b) == 0 })
+	}
+
+	if sorted := strings.Join(lines, "\n"); sorted != old {
+		e.replaceText(0, r.first, buffer.RuneLength(old), sorted)
+	}
+	e.goToLine(r.first)
+	return nil
+}
+
+// :normal, run keys in visual mode at the beginning of every line of the
+// range. An insert session left open by the keys is ended like with Esc
+func (e *Editor) normal(r lineRange, keys string) {
+	e.exitCommandMode()
+
+	y := r.first
+	for range r.last - r.first + 1 {
+		if !e.validLine(y) {
+			break
+		}
+
+		// the keys may add or remove lines, so the next line is found from
+		// the end of the document, which they are not supposed to change
+		left := e.InternalBuffer.LineCount() - y
+		e.cancelSelection()
+		e.goToLine(y)
+		e.runKeys(keys)
+		y = e.InternalBuffer.LineCount() - left + 1
+	}
+}
+
+// handle keys as if they were typed, and leave the mode they end up in. A
+// command the keys leave incomplete is cancelled, instead of waiting for keys
+// typed by the user
+func (e *Editor) runKeys(keys string) {
+	for _, r := range keys {
+		e.queue = append(e.queue, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	e.queueOnly = true
+	for len(e.queue) > 0 {
+		e.dispatch()
+	}
+	e.queueOnly = false
+	e.pending, e.register = pending{}, 0
+
+	switch e.Mode {
+	case EditMode:
+		e.queue = append(e.queue, tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone))
+		e.dispatch()
+	case CommandMode:
+		e.exitCommandMode()
+	case SearchMode:
+		e.exitSearchMode()
+	}
+}
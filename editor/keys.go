@@ -0,0 +1,96 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// the names of the keys that are not runes in the text of a macro, like <Esc>.
+// Control keys are written <C-a> to <C-z>
+var keyNames = map[tcell.Key]string{
+	tcell.KeyEsc:        "Esc",
+	tcell.KeyEnter:      "CR",
+	tcell.KeyTab:        "Tab",
+	tcell.KeyBackspace2: "BS",
+	tcell.KeyDelete:     "Del",
+	tcell.KeyUp:         "Up",
+	tcell.KeyDown:       "Down",
+	tcell.KeyLeft:       "Left",
+	tcell.KeyRight:      "Right",
+	tcell.KeyHome:       "Home",
+	tcell.KeyEnd:        "End",
+	tcell.KeyPgUp:       "PageUp",
+	tcell.KeyPgDn:       "PageDown",
+}
+
+// keysText writes keys as text, which can be edited and read back with
+// parseKeys
+func keysText(keys []tcell.Event) string {
+	var sb strings.Builder
+	for _, ev := range keys {
+		ev, ok := ev.(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+
+		k := ev.Key()
+		switch {
+		case k == tcell.KeyRune && ev.Rune() == '<':
+			sb.WriteString("<lt>")
+		case k == tcell.KeyRune:
+			sb.WriteRune(ev.Rune())
+		case keyNames[k] != "":
+			sb.WriteString("<" + keyNames[k] + ">")
+		case k >= tcell.KeyCtrlA && k <= tcell.KeyCtrlZ:
+			sb.WriteString("<C-" + string(rune('a'+k-tcell.KeyCtrlA)) + ">")
+		}
+	}
+	return sb.String()
+}
+
+// parseKeys reads keys written by keysText. A < that does not start the name
+// of a key is a rune like any other
+func parseKeys(s string) []tcell.Event {
+	var keys []tcell.Event
+	for s != "" {
+		if ev, n := parseKeyName(s); n > 0 {
+			keys = append(keys, ev)
+			s = s[n:]
+			continue
+		}
+
+		r := []rune(s)[0]
+		keys = append(keys, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		s = s[len(string(r)):]
+	}
+	return keys
+}
+
+// parseKeyName reads the key name s starts with, like <Esc>, and returns its
+// length in s, or 0 when there is none
+func parseKeyName(s string) (*tcell.EventKey, int) {
+	end := strings.IndexByte(s, '>')
+	if !strings.HasPrefix(s, "<") || end < 0 {
+		return nil, 0
+	}
+	name := s[1:end]
+
+	switch {
+	case strings.EqualFold(name, "lt"):
+		return tcell.NewEventKey(tcell.KeyRune, '<', tcell.ModNone), end + 1
+	case strings.EqualFold(name, "Space"):
+		return tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), end + 1
+	case len(name) == 3 && strings.HasPrefix(strings.ToUpper(name), "C-"):
+		c := name[2] | 0x20 // lowercase
+		if c >= 'a' && c <= 'z' {
+			return tcell.NewEventKey(tcell.KeyCtrlA+tcell.Key(c-'a'), rune(c), tcell.ModCtrl), end + 1
+		}
+	}
+	for k, n := range keyNames {
+		if strings.EqualFold(name, n) {
+			return tcell.NewEventKey(k, 0, tcell.ModNone), end + 1
+		}
+	}
+	return nil, 0
+}
@@ -0,0 +1,107 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eze-kiel/tide/str"
+	"github.com/gdamore/tcell/v2"
+)
+
+// EventSource is where the editor gets the events typed by the user from. It
+// is the screen, unless another source is injected
+type EventSource interface {
+	PollEvent() tcell.Event
+}
+
+// how deep macros can play other macros, so a macro playing itself ends
+const maxMacroDepth = 100
+
+// recording is a macro being recorded
+type recording struct {
+	register rune
+	append   bool // add the keys to the register instead of replacing it
+	keys     []tcell.Event
+}
+
+// start recording the typed keys into register r
+func (e *Editor) startRecording(r rune) {
+	name, appending, ok := registerName(r)
+	if !ok {
+		e.showError(fmt.Sprintf(str.InvalidRegisterErr, r))
+		return
+	}
+	e.recording = &recording{register: name, append: appending}
+}
+
+// stop recording, and keep the recorded keys as text in their register
+func (e *Editor) stopRecording() {
+	rec := e.recording
+	e.recording = nil
+
+	// the q stopping the recording is not part of the macro
+	keys := rec.keys[:max(len(rec.keys)-1, 0)]
+	reg := register{Text: keysText(keys), Kind: CharSelection}
+	if rec.append {
+		reg = appendRegister(e.registers[rec.register], reg)
+	}
+	e.setRegister(rec.register, reg)
+}
+
+// play the macro in register r n times. @ plays the last macro played again
+func (e *Editor) playMacro(r rune, n int) {
+	if r == '@' {
+		r = e.lastMacro
+	}
+	name, _, ok := registerName(r)
+	if !ok {
+		e.showError(fmt.Sprintf(str.InvalidRegisterErr, r))
+		return
+	}
+	if e.registers[name].Text == "" {
+		e.showError(fmt.Sprintf(str.EmptyRegisterErr, name))
+		return
+	}
+	if e.macroDepth >= maxMacroDepth {
+		e.showError(str.MacroTooDeepErr)
+		return
+	}
+	e.lastMacro = name
+
+	// the keys of the macro are handled before the ones already queued,
+	// which may be the rest of a macro playing this one
+	keys := parseKeys(e.registers[name].Text)
+	queued := e.queue
+	e.queue = nil
+	for range n {
+		e.queue = append(e.queue, keys...)
+	}
+
+	e.macroDepth++
+	for len(e.queue) > 0 {
+		e.dispatch()
+	}
+	e.macroDepth--
+	e.queue = queued
+}
+
+// :macro, set the text of the macro in a register. Without any text, the
+// command line is filled with the current one to edit it, and true is returned
+func (e *Editor) editMacro(args string) bool {
+	r, size := utf8.DecodeRuneInString(args)
+	name, _, ok := registerName(r)
+	text, spaced := strings.CutPrefix(args[size:], " ")
+	if !ok || args[size:] != "" && !spaced {
+		e.showError(str.InvalidArgumentErr + args)
+		return false
+	}
+
+	if args[size:] == "" {
+		e.CommandBuffer = fmt.Sprintf("macro %c %s", name, e.registers[name].Text)
+		e.CommandCursorPos = utf8.RuneCountInString(e.CommandBuffer)
+		return true
+	}
+	e.setRegister(name, register{Text: text, Kind: CharSelection})
+	return false
+}
@@ -0,0 +1,104 @@
+package editor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// DefaultMsgTimeout is how long the status messages are shown
+var DefaultMsgTimeout = 5 * time.Second
+
+// how many messages are kept for :messages
+const maxMessages = 100
+
+// severity of a status message, which gives its style
+type severity int
+
+const (
+	infoSeverity severity = iota
+	warningSeverity
+	errorSeverity
+)
+
+func (s severity) String() string {
+	switch s {
+	case warningSeverity:
+		return "warning"
+	case errorSeverity:
+		return "error"
+	}
+	return "info"
+}
+
+// message is a status message, shown on the right of the status line
+type message struct {
+	text     string
+	severity severity
+	time     time.Time // when it was shown
+	expires  time.Time // when it is no longer shown, never if zero
+}
+
+func (e *Editor) showInfo(text string) {
+	e.showMessage(text, infoSeverity)
+}
+
+func (e *Editor) showWarning(text string) {
+	e.showMessage(text, warningSeverity)
+}
+
+func (e *Editor) showError(text string) {
+	e.showMessage(text, errorSeverity)
+}
+
+// show a message on the status line for DefaultMsgTimeout, and keep it for
+// :messages. The main loop is woken up once the message expires, so that it
+// goes away even if no key is pressed
+func (e *Editor) showMessage(text string, sev severity) {
+	now := time.Now()
+	e.status = message{text: text, severity: sev, time: now, expires: now.Add(DefaultMsgTimeout)}
+	time.AfterFunc(DefaultMsgTimeout, func() {
+		e.Screen.PostEvent(tcell.NewEventInterrupt(nil))
+	})
+
+	e.messages = append(e.messages, e.status)
+	if len(e.messages) > maxMessages {
+		e.messages = e.messages[len(e.messages)-maxMessages:]
+	}
+}
+
+// show a question on the status line until another message replaces it. It
+// is not kept for :messages
+func (e *Editor) showPrompt(text string) {
+	e.status = message{text: text, severity: infoSeverity, time: time.Now()}
+}
+
+// the status message, if it is still to be shown
+func (e *Editor) statusMessage() (message, bool) {
+	m := e.status
+	if m.text == "" || !m.expires.IsZero() && !time.Now().Before(m.expires) {
+		return message{}, false
+	}
+	return m, true
+}
+
+// the style of the messages of severity s
+func (e *Editor) messageStyle(s severity) tcell.Style {
+	switch s {
+	case warningSeverity:
+		return e.theme.Warning
+	case errorSeverity:
+		return e.theme.Error
+	}
+	return e.theme.CommandLine
+}
+
+// the lines displayed by :messages, the oldest first
+func (e *Editor) messageLines() []string {
+	lines := make([]string, 0, len(e.messages))
+	for _, m := range e.messages {
+		lines = append(lines, fmt.Sprintf("%s %-7s %s", m.time.Format(time.TimeOnly), m.severity, m.text))
+	}
+	return lines
+}
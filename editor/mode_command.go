@@ -1,6 +1,8 @@
 package editor
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/eze-kiel/tide/str"
@@ -8,7 +10,7 @@ import (
 )
 
 func (e *Editor) commandModeRoutine() {
-	ev := e.Screen.PollEvent()
+	ev := e.pollEvent()
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
 		switch ev.Key() {
@@ -16,53 +18,67 @@ func (e *Editor) commandModeRoutine() {
 			e.exitCommandMode()
 		case tcell.KeyEnter:
 			if e.CommandBuffer != "" {
-				e.executeCommand(e.CommandBuffer)
+				// the command may fill the command line again
+				cmd := e.CommandBuffer
 				e.CommandBuffer = ""
 				e.CommandCursorPos = 0
+				e.executeCommand(cmd)
 			} else {
 				e.exitCommandMode()
 			}
 
-		case tcell.KeyBackspace, tcell.KeyBackspace2:
-			if e.CommandBuffer == "" {
-				e.exitCommandMode()
-			}
-			if e.CommandCursorPos > 0 {
-				e.CommandBuffer = e.CommandBuffer[:e.CommandCursorPos-1] + e.CommandBuffer[e.CommandCursorPos:]
-				e.CommandCursorPos--
-			}
+		default:
+			e.editCommandLine(ev)
+		}
+	}
+}
 
-		case tcell.KeyDelete:
-			if e.CommandCursorPos < len(e.CommandBuffer) {
-				e.CommandBuffer = e.CommandBuffer[:e.CommandCursorPos] + e.CommandBuffer[e.CommandCursorPos+1:]
-			}
+// edit the command line, which is also used to type search patterns. The
+// cursor position is in runes
+func (e *Editor) editCommandLine(ev *tcell.EventKey) {
+	line := []rune(e.CommandBuffer)
+	e.CommandCursorPos = min(max(e.CommandCursorPos, 0), len(line))
+	switch ev.Key() {
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if e.CommandBuffer == "" {
+			e.exitCommandMode()
+		}
+		if e.CommandCursorPos > 0 {
+			e.CommandBuffer = string(line[:e.CommandCursorPos-1]) + string(line[e.CommandCursorPos:])
+			e.CommandCursorPos--
+		}
 
-		case tcell.KeyLeft:
-			if e.CommandCursorPos > 0 {
-				e.CommandCursorPos--
-			}
+	case tcell.KeyDelete:
+		if e.CommandCursorPos < len(line) {
+			e.CommandBuffer = string(line[:e.CommandCursorPos]) + string(line[e.CommandCursorPos+1:])
+		}
 
-		case tcell.KeyRight:
-			if e.CommandCursorPos < len(e.CommandBuffer) {
-				e.CommandCursorPos++
-			}
+	case tcell.KeyLeft:
+		if e.CommandCursorPos > 0 {
+			e.CommandCursorPos--
+		}
 
-		case tcell.KeyRune:
-			e.CommandBuffer = e.CommandBuffer[:e.CommandCursorPos] + string(ev.Rune()) + e.CommandBuffer[e.CommandCursorPos:]
+	case tcell.KeyRight:
+		if e.CommandCursorPos < len(line) {
 			e.CommandCursorPos++
+		}
 
-		case tcell.KeyHome:
-			e.CommandCursorPos = 0
+	case tcell.KeyRune:
+		e.CommandBuffer = string(line[:e.CommandCursorPos]) + string(ev.Rune()) + string(line[e.CommandCursorPos:])
+		e.CommandCursorPos++
 
-		case tcell.KeyEnd:
-			e.CommandCursorPos = len(e.CommandBuffer)
-		}
+	case tcell.KeyHome:
+		e.CommandCursorPos = 0
+
+	case tcell.KeyEnd:
+		e.CommandCursorPos = len(line)
 	}
 }
 
 func (e *Editor) exitCommandMode() {
 	e.Mode = VisualMode
 	e.CommandBuffer = ""
+	e.CommandCursorPos = 0
 
 	e.updateRenderCursor()
 }
@@ -70,18 +86,34 @@ func (e *Editor) exitCommandMode() {
 func (e *Editor) executeCommand(cmd string) {
 	cmd = strings.TrimSpace(cmd)
 
-	parts := strings.Fields(cmd)
-	if len(parts) == 0 {
+	r, cmd, err := e.parseRange(cmd)
+	if err != nil {
+		e.showError(err.Error())
+		e.exitCommandMode()
+		return
+	}
+	if isSubstitute(cmd) {
+		e.substitute(r, cmd[1:])
+		e.exitCommandMode()
 		return
 	}
 
-	switch parts[0] {
-	case "q", "quit":
-		if !e.fileChanged {
-			e.Quit()
+	name, args := splitCommand(cmd)
+	if name == "" {
+		// a range alone jumps to its last line
+		if r.given && strings.TrimSpace(cmd) == "" {
+			e.goToLine(r.last)
+		} else if cmd != "" {
+			e.showError(str.UnknownCommandErr + cmd)
 		}
-		e.StatusMsg = str.FileModified
-		e.StatusTimeout = DefaultMsgTimeout
+		e.exitCommandMode()
+		return
+	}
+	parts := append([]string{name}, strings.Fields(args)...)
+
+	switch name {
+	case "q", "quit":
+		e.quitIfSaved()
 
 	case "q!", "quit!", "qq":
 		e.Quit()
@@ -97,11 +129,171 @@ func (e *Editor) executeCommand(cmd string) {
 			e.Filename = parts[1]
 		}
 		e.SaveToFile()
-		e.Quit()
+		e.quitIfSaved()
+
+	case "e", "edit":
+		if len(parts) < 2 {
+			e.showError(str.MissingFilenameErr)
+			break
+		}
+		if err := e.Open(parts[1]); err != nil {
+			e.showError(str.CannotOpenErr + err.Error())
+		}
+
+	case "bn", "bnext":
+		e.cycleDocument(1)
+
+	case "bp", "bprevious":
+		e.cycleDocument(-1)
+
+	case "b", "buffer":
+		n := 0
+		if len(parts) > 1 {
+			n, _ = strconv.Atoi(parts[1])
+		}
+		if n < 1 || n > len(e.Documents) {
+			e.showError(str.NoSuchBufferErr)
+			break
+		}
+		e.switchDocument(e.Documents[n-1])
+
+	case "ls", "buffers":
+		e.showLines(e.bufferList())
+
+	case "bd", "bdelete":
+		if e.fileChanged {
+			e.showWarning(str.FileModified)
+			break
+		}
+		e.closeDocument()
+
+	case "bd!", "bdelete!":
+		e.closeDocument()
+
+	case "sp", "split", "vs", "vsplit":
+		e.splitWindow(strings.HasPrefix(parts[0], "v"))
+		if len(parts) > 1 {
+			if err := e.Open(parts[1]); err != nil {
+				e.showError(str.CannotOpenErr + err.Error())
+			}
+		}
+
+	case "d", "delete":
+		e.deleteLines(r)
+
+	case "y", "yank":
+		e.yankLines(r)
+
+	case "m", "move", "t", "co", "copy":
+		dest, err := e.parseDestination(args)
+		if err != nil {
+			e.showError(err.Error())
+			break
+		}
+		if !strings.HasPrefix(name, "m") {
+			e.copyLines(r, dest)
+		} else if err := e.moveLines(r, dest); err != nil {
+			e.showError(err.Error())
+		}
+
+	case "j", "join":
+		e.joinLines(r)
+
+	case "sor", "sort", "sor!", "sort!":
+		// the whole document is sorted by default
+		if !r.given {
+			r = lineRange{first: 0, last: e.InternalBuffer.LineCount() - 1}
+		}
+		if err := e.sortLines(r, strings.HasSuffix(name, "!"), args); err != nil {
+			e.showError(err.Error())
+		}
+
+	case "norm", "normal":
+		e.normal(r, args)
+
+	case "macro":
+		if e.editMacro(args) {
+			// stay on the command line to edit the macro
+			return
+		}
+
+	case "mes", "messages":
+		e.showLines(e.messageLines())
+
+	case "reg", "registers":
+		e.showLines(e.registerLines())
+
+	case "noh", "nohlsearch":
+		e.clearSearchHighlight()
+
+	case "clo", "close":
+		e.closeWindow()
+
+	case "on", "only":
+		e.onlyWindow()
+
+	case "res", "resize", "vres", "vresize":
+		vertical := strings.HasPrefix(parts[0], "v")
+		if len(parts) < 2 {
+			e.showError(str.MissingSizeErr)
+			break
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			e.showError(str.MissingSizeErr)
+			break
+		}
+		// a signed size is relative, otherwise it is the wanted size
+		if !strings.HasPrefix(parts[1], "+") && !strings.HasPrefix(parts[1], "-") {
+			if vertical {
+				n -= e.area.w
+			} else {
+				n -= e.area.h
+			}
+		}
+		e.resizeWindow(n, vertical)
 
 	default:
-		e.StatusMsg = str.UnknownCommandErr + parts[0]
-		e.StatusTimeout = DefaultMsgTimeout
+		e.showError(str.UnknownCommandErr + parts[0])
 	}
 	e.exitCommandMode()
 }
+
+// quit the editor, unless a document has unsaved changes
+func (e *Editor) quitIfSaved() {
+	d := e.modifiedDocument()
+	if d == nil {
+		e.Quit()
+	}
+
+	if d == e.Document {
+		e.showWarning(str.FileModified)
+	} else {
+		e.showWarning(fmt.Sprintf(str.OtherFileModified, d.name()))
+	}
+}
+
+// the lines displayed by :ls, one per document
+func (e *Editor) bufferList() []string {
+	// the documents shown keep the cursor of their window, the current one
+	// coming last
+	for _, w := range e.layout.windows() {
+		w.save()
+	}
+	e.Window.save()
+
+	lines := make([]string, 0, len(e.Documents))
+	for i, d := range e.Documents {
+		flags := " "
+		if d == e.Document {
+			flags = "%"
+		}
+		if d.fileChanged {
+			flags += "+"
+		} else {
+			flags += " "
+		}
+		lines = append(lines, fmt.Sprintf("%3d %s %-30q line %d", i+1, flags, d.name(), d.InternalCursor.Y+1))
+	}
+	return lines
+}
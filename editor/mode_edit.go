@@ -5,31 +5,32 @@ import (
 )
 
 func (e *Editor) editModeRoutine() {
-	ev := e.Screen.PollEvent()
+	ev := e.pollEvent()
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
 		switch ev.Key() {
 		case tcell.KeyEsc:
+			e.finishBlockInsert()
 			if e.autoSaveOnSwitch {
 				e.SaveToFile()
 			}
 			e.SwitchMode()
 		case tcell.KeyRight:
-			e.moveInternalCursor(1, 0)
+			e.atEachCursor(func() { e.moveInternalCursor(1, 0) })
 		case tcell.KeyLeft:
-			e.moveInternalCursor(-1, 0)
+			e.atEachCursor(func() { e.moveInternalCursor(-1, 0) })
 		case tcell.KeyDown:
-			e.moveInternalCursor(0, 1)
+			e.atEachCursor(func() { e.moveInternalCursor(0, 1) })
 		case tcell.KeyUp:
-			e.moveInternalCursor(0, -1)
+			e.atEachCursor(func() { e.moveInternalCursor(0, -1) })
 		case tcell.KeyRune:
-			e.insertRune(ev.Rune())
+			e.atEachCursor(func() { e.insertRune(ev.Rune()) })
 		case tcell.KeyEnter:
-			e.insertNewlineAtCursor()
+			e.atEachCursor(e.insertNewlineAtCursor)
 		case tcell.KeyBackspace2:
-			e.deleteRuneBeforeCursor()
+			e.atEachCursor(e.deleteRuneBeforeCursor)
 		case tcell.KeyTab:
-			e.insertRune('\t')
+			e.atEachCursor(func() { e.insertRune('\t') })
 		}
 	}
 }
@@ -5,22 +5,19 @@ import (
 )
 
 func (e *Editor) visualModeRoutine() {
-	ev := e.Screen.PollEvent()
+	ev := e.pollEvent()
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
+		if e.handlePending(ev) {
+			return
+		}
+
 		switch ev.Key() {
-		case tcell.KeyRight:
-			e.cancelSelection()
-			e.moveInternalCursor(1, 0)
-		case tcell.KeyLeft:
-			e.cancelSelection()
-			e.moveInternalCursor(-1, 0)
-		case tcell.KeyDown:
-			e.cancelSelection()
-			e.moveInternalCursor(0, 1)
-		case tcell.KeyUp:
+		case tcell.KeyEsc:
+			if !e.selecting() {
+				e.clearCursors()
+			}
 			e.cancelSelection()
-			e.moveInternalCursor(0, -1)
 		case tcell.KeyCtrlU:
 			e.moveInternalCursor(0, -e.fastJumpLength)
 		case tcell.KeyCtrlD:
@@ -28,44 +25,96 @@ func (e *Editor) visualModeRoutine() {
 		case tcell.KeyRune:
 			switch ev.Rune() {
 			case 'd':
-				if e.Selection.Content == "" {
-					e.deleteRuneAtCursor()
-				} else {
-					e.deleteSelection()
-				}
-			case 'e':
-				e.moveInternalCursor(0, len(e.InternalBuffer.Data)-1)
-			case 't':
-				e.moveInternalCursor(0, -len(e.InternalBuffer.Data)) // the overflow is handled in another place, but hacky
-			case 'h':
-				e.moveInternalCursor(-1000, 0) // hacky lol
-			case 'l':
-				e.moveInternalCursor(1000, 0) // hacky lol
+				e.cutSelection()
+			case 'c':
+				e.cutSelection()
+				e.SwitchMode()
 			case 'o':
-				e.insertNewlineUnder()
+				e.cancelSelection()
+				e.atEachCursor(e.insertNewlineUnder)
 				e.SwitchMode()
 			case 'O':
-				e.insertNewlineAbove()
+				e.cancelSelection()
+				e.atEachCursor(e.insertNewlineAbove)
 				e.SwitchMode()
 			case ':':
 				e.Mode = CommandMode
+				e.repeat.ex = true
+				// commands apply to the selected lines
+				if e.selecting() {
+					e.cancelSelection()
+					e.CommandBuffer = "'<,'>"
+					e.CommandCursorPos = len(e.CommandBuffer)
+				}
+			case '/':
+				e.enterSearchMode(false)
+			case '?':
+				e.enterSearchMode(true)
+			case 'n':
+				e.searchNext(false)
+			case 'N':
+				e.searchNext(true)
 			case 'i':
 				e.SwitchMode()
+			case 'I':
+				if e.Selection.Kind == BlockSelection {
+					e.startBlockInsert(false)
+					break
+				}
+				e.cancelSelection()
+				e.moveCursor(firstNonBlank)
+				e.SwitchMode()
+			case 'A':
+				if e.Selection.Kind == BlockSelection {
+					e.startBlockInsert(true)
+					break
+				}
+				e.cancelSelection()
+				e.moveCursor(lineEnd)
+				e.SwitchMode()
+			case 'J':
+				e.addCursorVertically(1)
+			case 'K':
+				e.addCursorVertically(-1)
+			case 'C':
+				e.addCursorsOnSelection()
 			case 'r':
 				e.replaceRuneUnder()
+			case 'v':
+				e.toggleSelection(CharSelection)
 			case 'x':
 				e.selectLine()
-			case 'a':
-				e.cancelSelection()
 			case 'y':
 				e.copySelection()
 			case 'p':
-				e.pasteUnder()
+				e.paste()
+			case '>':
+				first, last := e.selectedLines()
+				e.indentLines(first, last, 1)
+			case '<':
+				first, last := e.selectedLines()
+				e.indentLines(first, last, -1)
 			case 'u':
 				e.undo()
+			case '-':
+				e.earlier()
+			case '+':
+				e.later()
 			}
+		case tcell.KeyDelete:
+			e.atEachCursor(e.deleteRuneAtCursor)
+		case tcell.KeyCtrlP:
+			e.cyclePaste()
+		case tcell.KeyCtrlN:
+			e.addCursorOnNextWord()
+		case tcell.KeyCtrlV:
+			e.toggleSelection(BlockSelection)
+		case tcell.KeyCtrlR:
+			e.redo()
+		case tcell.KeyCtrlW:
+			e.windowCommand()
 		case tcell.KeyCtrlC:
-			e.toggleCommentLine()
+			e.toggleComment()
 		}
 	}
 }
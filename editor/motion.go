@@ -0,0 +1,237 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+)
+
+// motion returns the position the cursor goes to from c in b. Motions do not
+// move anything themselves, so they can be used to find the text commands
+// apply to
+type motion func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor
+
+// move every cursor with m
+func (e *Editor) moveCursor(m motion) {
+	e.atEachCursor(func() {
+		e.InternalCursor = m(e.InternalBuffer, e.InternalCursor)
+		e.moveInternalCursor(0, 0)
+	})
+}
+
+const (
+	blankClass = iota
+	wordClass
+	punctClass
+)
+
+// runeClass tells what kind of word r is part of. A word is made of letters,
+// digits and underscores, or of other non-blank runes, while a WORD (big) is
+// made of any non-blank runes
+func runeClass(r rune, big bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return blankClass
+	case big || isWordRune(r):
+		return wordClass
+	default:
+		return punctClass
+	}
+}
+
+// an empty line starts at off
+func emptyLineAt(b buffer.Buffer, off int) bool {
+	return b.RuneAt(off) == '\n' && (off == 0 || b.RuneAt(off-1) == '\n')
+}
+
+func position(b buffer.Buffer, off int) cursor.Cursor {
+	x, y := b.Pos(off)
+	return cursor.Cursor{X: x, Y: y}
+}
+
+// wordForward goes to the beginning of the next word. Empty lines count as
+// words
+func wordForward(big bool) motion {
+	return func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+		off, n := b.Offset(c.X, c.Y), b.Len()
+		if off >= n {
+			return c
+		}
+
+		if class := runeClass(b.RuneAt(off), big); class != blankClass {
+			for off < n && runeClass(b.RuneAt(off), big) == class {
+				off++
+			}
+		}
+		for off < n && runeClass(b.RuneAt(off), big) == blankClass {
+			if off+1 < n && emptyLineAt(b, off+1) {
+				return position(b, off+1)
+			}
+			off++
+		}
+		return position(b, off)
+	}
+}
+
+// wordBackward goes to the beginning of the word, or of the previous one when
+// already there
+func wordBackward(big bool) motion {
+	return func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+		off := b.Offset(c.X, c.Y) - 1
+		if off < 0 {
+			return c
+		}
+
+		for off > 0 && runeClass(b.RuneAt(off), big) == blankClass {
+			if emptyLineAt(b, off) {
+				return position(b, off)
+			}
+			off--
+		}
+		class := runeClass(b.RuneAt(off), big)
+		for off > 0 && runeClass(b.RuneAt(off-1), big) == class {
+			off--
+		}
+		return position(b, off)
+	}
+}
+
+// wordEnd goes to the end of the word, or of the next one when already there
+func wordEnd(big bool) motion {
+	return func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+		off, n := b.Offset(c.X, c.Y)+1, b.Len()
+
+		for off < n && runeClass(b.RuneAt(off), big) == blankClass {
+			off++
+		}
+		if off >= n {
+			return position(b, n)
+		}
+		class := runeClass(b.RuneAt(off), big)
+		for off+1 < n && runeClass(b.RuneAt(off+1), big) == class {
+			off++
+		}
+		return position(b, off)
+	}
+}
+
+// paragraphForward goes to the empty line after the paragraph, or to the end
+// of the document after the last one
+func paragraphForward(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	y, lines := c.Y, b.LineCount()
+	for y < lines && b.LineLen(y) == 0 {
+		y++
+	}
+	for y < lines && b.LineLen(y) > 0 {
+		y++
+	}
+	if y >= lines {
+		return cursor.Cursor{X: b.LineLen(lines - 1), Y: lines - 1}
+	}
+	return cursor.Cursor{X: 0, Y: y}
+}
+
+// paragraphBackward goes to the empty line before the paragraph, or to the
+// beginning of the document before the first one
+func paragraphBackward(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	y := c.Y
+	for y >= 0 && b.LineLen(y) == 0 {
+		y--
+	}
+	for y >= 0 && b.LineLen(y) > 0 {
+		y--
+	}
+	return cursor.Cursor{X: 0, Y: max(y, 0)}
+}
+
+var brackets = map[rune]rune{
+	'(': ')', '[': ']', '{': '}',
+	')': '(', ']': '[', '}': '{',
+}
+
+// matchingBracket goes to the bracket matching the first one found from the
+// cursor to the end of the line. The cursor stays where it is if there is none
+func matchingBracket(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	line := []rune(b.Line(c.Y))
+	x := c.X
+	for x < len(line) && brackets[line[x]] == 0 {
+		x++
+	}
+	if x >= len(line) {
+		return c
+	}
+
+	open := line[x]
+	match := brackets[open]
+	step := 1
+	if strings.ContainsRune(")]}", open) {
+		step = -1
+	}
+
+	depth := 0
+	for off := b.Offset(x, c.Y); off >= 0 && off < b.Len(); off += step {
+		switch b.RuneAt(off) {
+		case open:
+			depth++
+		case match:
+			depth--
+			if depth == 0 {
+				return position(b, off)
+			}
+		}
+	}
+	return c
+}
+
+// firstNonBlank goes to the first rune of the line that is not a blank
+func firstNonBlank(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	line := []rune(b.Line(c.Y))
+	x := 0
+	for x < len(line) && (line[x] == ' ' || line[x] == '\t') {
+		x++
+	}
+	return cursor.Cursor{X: x, Y: c.Y}
+}
+
+func runeLeft(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	return cursor.Cursor{X: max(c.X-1, 0), Y: c.Y}
+}
+
+func runeRight(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	return cursor.Cursor{X: min(c.X+1, b.LineLen(c.Y)), Y: c.Y}
+}
+
+// lineUp goes to the previous line, at the same column or at its end when it
+// is too short
+func lineUp(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	y := max(c.Y-1, 0)
+	return cursor.Cursor{X: min(c.X, b.LineLen(y)), Y: y}
+}
+
+// lineDown goes to the next line, at the same column or at its end when it is
+// too short
+func lineDown(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	y := min(c.Y+1, b.LineCount()-1)
+	return cursor.Cursor{X: min(c.X, b.LineLen(y)), Y: y}
+}
+
+func lineStart(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	return cursor.Cursor{X: 0, Y: c.Y}
+}
+
+func lineEnd(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	return cursor.Cursor{X: b.LineLen(c.Y), Y: c.Y}
+}
+
+// documentStart goes to the first line, at the same column
+func documentStart(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	return cursor.Cursor{X: min(c.X, b.LineLen(0)), Y: 0}
+}
+
+// documentEnd goes to the last line, at the same column
+func documentEnd(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+	y := b.LineCount() - 1
+	return cursor.Cursor{X: min(c.X, b.LineLen(y)), Y: y}
+}
@@ -0,0 +1,345 @@
+package editor
+
+import (
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/gdamore/tcell/v2"
+)
+
+// how the text between the cursor and the target of a motion is covered by an
+// operator
+const (
+	exclusive = iota // up to the target, which is not included
+	inclusive        // up to the target, included
+	linewise         // every line from the cursor one to the target one
+)
+
+// pending is the command being typed in visual mode, like 3dw: a count, an
+// operator waiting for a motion, and the count typed after the operator
+type pending struct {
+	count    int
+	operator string // d, c, y, >, < or gc
+	opCount  int
+	prefix   string // g, i, a, q, @ or " while waiting for the key completing the command
+	register bool   // whether a register has been selected for the command
+}
+
+func (p pending) active() bool {
+	return p.count > 0 || p.operator != "" || p.prefix != "" || p.register
+}
+
+// times returns how many times the motion is repeated
+func (p pending) times() int {
+	return max(1, p.count) * max(1, p.opCount)
+}
+
+var operators = map[string]bool{
+	"d": true, "c": true, "y": true, ">": true, "<": true, "gc": true,
+}
+
+// motionKey returns the motion bound to a key, and how operators apply to it
+func motionKey(ev *tcell.EventKey) (m motion, kind int, ok bool) {
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		return runeLeft, exclusive, true
+	case tcell.KeyRight:
+		return runeRight, exclusive, true
+	case tcell.KeyUp:
+		return lineUp, linewise, true
+	case tcell.KeyDown:
+		return lineDown, linewise, true
+	case tcell.KeyCtrlE:
+		// e is the end of the document, so the end of a word is Ctrl-E, and
+		// the end of a WORD E, like w and W
+		return wordEnd(false), inclusive, true
+	case tcell.KeyRune:
+	default:
+		return nil, 0, false
+	}
+
+	switch ev.Rune() {
+	case 'e':
+		return documentEnd, linewise, true
+	case 't':
+		return documentStart, linewise, true
+	case 'h', '0':
+		return lineStart, exclusive, true
+	case 'l', '$':
+		return lineEnd, exclusive, true
+	case '^':
+		return firstNonBlank, exclusive, true
+	case 'w':
+		return wordForward(false), exclusive, true
+	case 'W':
+		return wordForward(true), exclusive, true
+	case 'b':
+		return wordBackward(false), exclusive, true
+	case 'B':
+		return wordBackward(true), exclusive, true
+	case 'E':
+		return wordEnd(true), inclusive, true
+	case '}':
+		return paragraphForward, exclusive, true
+	case '{':
+		return paragraphBackward, exclusive, true
+	case '%':
+		return matchingBracket, inclusive, true
+	}
+	return nil, 0, false
+}
+
+// handle ev if it is part of a count, operator and motion command. It returns
+// false when the key is left to the visual mode routine
+func (e *Editor) handlePending(ev *tcell.EventKey) bool {
+	p := &e.pending
+
+	if ev.Key() == tcell.KeyEsc && p.active() {
+		*p = pending{}
+		return true
+	}
+
+	if ev.Key() == tcell.KeyRune {
+		r := ev.Rune()
+
+		// " selects the register of the command
+		if p.prefix == "\"" {
+			p.prefix = ""
+			e.selectRegister(r)
+			p.register = e.register != 0
+			return true
+		}
+		if r == '"' && p.operator == "" && p.prefix == "" {
+			p.prefix = string(r)
+			return true
+		}
+
+		// 0 is a motion, unless it is part of a count
+		if r >= '1' && r <= '9' || r == '0' && (p.opCount > 0 || p.operator == "" && p.count > 0) {
+			if p.operator != "" {
+				p.opCount = p.opCount*10 + int(r-'0')
+			} else {
+				p.count = p.count*10 + int(r-'0')
+			}
+			return true
+		}
+
+		// . makes the last change again, with the count instead of its own
+		if r == '.' && p.operator == "" && p.prefix == "" {
+			n := p.count
+			*p = pending{}
+			e.repeatChange(n)
+			return true
+		}
+
+		// i and a start a text object when an operator waits for what it
+		// applies to, or when some text is selected. While selecting, a key
+		// that is not an object is handled as if typed alone
+		if p.prefix == "i" || p.prefix == "a" {
+			op, around := p.operator, p.prefix == "a"
+			*p = pending{}
+			obj, ok := textObjectKey(r)
+			switch {
+			case !ok:
+				return op != ""
+			case op == "":
+				e.selectTextObject(obj, around)
+			default:
+				e.atEachCursor(func() {
+					e.operateTextObject(op, obj, around)
+				})
+			}
+			return true
+		}
+		// q starts or stops recording a macro, and @ plays one count times
+		if p.prefix == "q" || p.prefix == "@" {
+			prefix, n := p.prefix, max(1, p.count)
+			*p = pending{}
+			if prefix == "q" {
+				e.startRecording(r)
+			} else {
+				e.playMacro(r, n)
+			}
+			return true
+		}
+		if r == 'q' && e.recording != nil && !p.active() {
+			e.stopRecording()
+			return true
+		}
+		if (r == 'q' || r == '@') && p.operator == "" && p.prefix == "" {
+			p.prefix = string(r)
+			return true
+		}
+
+		if (r == 'i' || r == 'a') && p.prefix == "" && (p.operator != "" || e.selecting()) {
+			p.prefix = string(r)
+			return true
+		}
+
+		key, prefixed := p.prefix+string(r), p.prefix != ""
+		p.prefix = ""
+		if key == "g" {
+			p.prefix = key
+			return true
+		}
+		if prefixed && !operators[key] {
+			*p = pending{}
+			return true
+		}
+
+		if operators[key] {
+			switch {
+			case e.selecting():
+				// the selection is what the operator applies to, which the
+				// visual mode routine handles for single key operators
+				*p = pending{}
+				if key != "gc" {
+					return false
+				}
+				e.toggleComment()
+				e.cancelSelection()
+			case p.operator == "":
+				p.operator = key
+			case p.operator == key || p.operator == "gc" && key == "c":
+				// doubling the operator applies it to count lines, gcc being
+				// the double of gc
+				op, n := p.operator, p.times()
+				*p = pending{}
+				e.atEachCursor(func() {
+					y := e.InternalCursor.Y
+					e.operateLines(op, lineRange{first: y, last: min(y+n-1, e.InternalBuffer.LineCount()-1)})
+				})
+			default:
+				*p = pending{}
+			}
+			return true
+		}
+	}
+
+	m, kind, ok := motionKey(ev)
+	if !ok {
+		// anything else cancels the command being typed
+		wasOperator := p.operator != ""
+		*p = pending{}
+		return wasOperator
+	}
+
+	op, n := p.operator, p.times()
+	*p = pending{}
+	if op == "" {
+		e.moveCursor(func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+			for range n {
+				c = m(b, c)
+			}
+			return c
+		})
+		return true
+	}
+
+	// cw changes up to the end of the word, not up to the next one
+	if op == "c" && ev.Key() == tcell.KeyRune && (ev.Rune() == 'w' || ev.Rune() == 'W') {
+		b, c := e.InternalBuffer, e.InternalCursor
+		if off := b.Offset(c.X, c.Y); off < b.Len() && runeClass(b.RuneAt(off), false) != blankClass {
+			big, words := ev.Rune() == 'W', n
+			m = func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+				c = endOfWord(big)(b, c)
+				for range words - 1 {
+					c = wordEnd(big)(b, c)
+				}
+				return c
+			}
+			kind, n = inclusive, 1
+		}
+	}
+
+	e.atEachCursor(func() {
+		e.applyOperator(op, m, kind, n)
+	})
+	return true
+}
+
+// endOfWord goes to the last rune of the word the cursor is in
+func endOfWord(big bool) motion {
+	return func(b buffer.Buffer, c cursor.Cursor) cursor.Cursor {
+		off, n := b.Offset(c.X, c.Y), b.Len()
+		class := runeClass(b.RuneAt(off), big)
+		for off+1 < n && runeClass(b.RuneAt(off+1), big) == class {
+			off++
+		}
+		return position(b, off)
+	}
+}
+
+// apply the operator to the text from the cursor to where the motion repeated
+// n times goes
+func (e *Editor) applyOperator(op string, m motion, kind, n int) {
+	b := e.InternalBuffer
+	start, end := e.InternalCursor, e.InternalCursor
+	for range n {
+		end = m(b, end)
+	}
+
+	if end.Y < start.Y || (end.Y == start.Y && end.X < start.X) {
+		start, end = end, start
+	}
+	if kind == linewise {
+		e.operateLines(op, lineRange{first: start.Y, last: end.Y})
+		return
+	}
+
+	from, to := b.Offset(start.X, start.Y), b.Offset(end.X, end.Y)
+	switch {
+	case kind == inclusive:
+		to = min(to+1, b.Len())
+	case end.X == 0 && end.Y > start.Y:
+		// an exclusive motion ending at the beginning of a line stops at the
+		// end of the previous one
+		to = b.Offset(b.LineLen(end.Y-1), end.Y-1)
+	}
+	e.operateText(op, from, to)
+}
+
+// apply the operator to the text between the from and to offsets
+func (e *Editor) operateText(op string, from, to int) {
+	x, y := e.InternalBuffer.Pos(from)
+	switch op {
+	case "d", "c", "y":
+		if op != "y" || to > from {
+			e.yank(e.InternalBuffer.Slice(from, to), CharSelection)
+		}
+		if op != "y" {
+			e.deleteText(x, y, to-from)
+		}
+		e.InternalCursor = cursor.Cursor{X: x, Y: y}
+		e.moveInternalCursor(0, 0)
+		if op == "c" {
+			e.Mode = EditMode
+		}
+	default:
+		_, last := e.InternalBuffer.Pos(max(from, to-1))
+		e.operateLines(op, lineRange{first: y, last: last})
+	}
+}
+
+// apply the operator to the lines of r
+func (e *Editor) operateLines(op string, r lineRange) {
+	switch op {
+	case "d":
+		e.deleteLines(r)
+	case "y":
+		e.yankLines(r)
+	case "c":
+		e.yankLines(r)
+		e.replaceText(0, r.first, buffer.RuneLength(e.linesText(r)), "")
+		e.goToLine(r.first)
+		e.Mode = EditMode
+	case ">":
+		e.indentLines(r.first, r.last, 1)
+		e.goToLine(r.first)
+	case "<":
+		e.indentLines(r.first, r.last, -1)
+		e.goToLine(r.first)
+	case "gc":
+		e.commentLines(r.first, r.last)
+		e.goToLine(r.first)
+	}
+}
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/eze-kiel/tide/str"
+)
+
+// lineRange is the range of lines a command applies to, both included
+type lineRange struct {
+	first, last int
+	given       bool // whether the range has been typed, or is the current line
+}
+
+// parseRange reads the range at the beginning of cmd, and returns the rest of
+// the command. A range is '%' for the whole document, or one or two addresses
+// separated by a comma. Without a range, the command applies to the current
+// line
+func (e *Editor) parseRange(cmd string) (lineRange, string, error) {
+	y := e.InternalCursor.Y
+	r := lineRange{first: y, last: y}
+
+	if rest, ok := strings.CutPrefix(cmd, "%"); ok {
+		r = lineRange{first: 0, last: e.InternalBuffer.LineCount() - 1, given: true}
+		return r, rest, nil
+	}
+
+	first, rest, ok, err := e.parseAddress(cmd)
+	if err != nil || !ok {
+		return r, cmd, err
+	}
+	// line 0 stands for the first line, like in vi, but not an address that
+	// ends up before it, like .-1 on the first line
+	if isZero(cmd[:len(cmd)-len(rest)]) {
+		first = 0
+	}
+	r = lineRange{first: first, last: first, given: true}
+
+	if after, found := strings.CutPrefix(rest, ","); found {
+		last, rest2, ok, err := e.parseAddress(after)
+		if err != nil {
+			return r, cmd, err
+		}
+		if !ok {
+			return r, cmd, errors.New(str.InvalidRangeErr)
+		}
+		if isZero(after[:len(after)-len(rest2)]) {
+			last = 0
+		}
+		r.last, rest = last, rest2
+	}
+
+	if !e.validLine(r.first) || !e.validLine(r.last) {
+		return r, cmd, errors.New(str.InvalidRangeErr)
+	}
+	if r.first > r.last {
+		r.first, r.last = r.last, r.first
+	}
+	return r, rest, nil
+}
+
+// parseAddress reads the line address at the beginning of cmd. It is a line
+// number, '.' for the current line, '$' for the last one, or '< and '> for the
+// first and last lines of the last selection. Any number of +N and -N offsets
+// can follow, and an offset alone is relative to the current line. The line is
+// not checked, as 0 is valid for some commands and is returned as -1
+func (e *Editor) parseAddress(cmd string) (y int, rest string, ok bool, err error) {
+	y, rest = e.InternalCursor.Y, cmd
+
+	switch {
+	case strings.HasPrefix(rest, "."):
+		rest, ok = rest[1:], true
+	case strings.HasPrefix(rest, "$"):
+		y, rest, ok = e.InternalBuffer.LineCount()-1, rest[1:], true
+	case strings.HasPrefix(rest, "'<"), strings.HasPrefix(rest, "'>"):
+		if !e.lastSelection.given {
+			return 0, cmd, false, errors.New(str.MarkNotSetErr)
+		}
+		y = e.lastSelection.first
+		if rest[1] == '>' {
+			y = e.lastSelection.last
+		}
+		rest, ok = rest[2:], true
+	default:
+		if n, after := leadingNumber(rest); after != rest {
+			y, rest, ok = n-1, after, true
+		}
+	}
+
+	for len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+		sign := 1
+		if rest[0] == '-' {
+			sign = -1
+		}
+
+		// a sign alone counts for one line
+		n, after := leadingNumber(rest[1:])
+		if after == rest[1:] {
+			n = 1
+		}
+		y, rest, ok = y+sign*n, after, true
+	}
+
+	return y, rest, ok, nil
+}
+
+// isZero tells whether the address addr is the line number 0 alone
+func isZero(addr string) bool {
+	n, rest := leadingNumber(addr)
+	return rest == "" && addr != "" && n == 0
+}
+
+// leadingNumber reads the number at the beginning of s, and returns what is
+// after it. s is returned as is when it does not start with a number
+func leadingNumber(s string) (int, string) {
+	n := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if n < 0 {
+		n = len(s)
+	}
+	v, err := strconv.Atoi(s[:n])
+	if err != nil {
+		return 0, s
+	}
+	return v, s[n:]
+}
+
+func (e *Editor) validLine(y int) bool {
+	return y >= 0 && y < e.InternalBuffer.LineCount()
+}
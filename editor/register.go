@@ -0,0 +1,228 @@
+package editor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eze-kiel/tide/state"
+	"github.com/eze-kiel/tide/str"
+)
+
+// how many yanked or deleted texts the ring keeps
+const ringSize = 10
+
+// register is some yanked or deleted text, along with the kind of selection
+// it comes from, which tells how it is pasted
+type register struct {
+	Text string
+	Kind int // CharSelection, LineSelection or BlockSelection
+}
+
+// pasted is the last paste, whose text can be replaced by the previous one of
+// the ring as long as nothing changed the document since
+type pasted struct {
+	document *Document
+	seq      int // the history state the paste leads to
+	index    int // where the pasted text is in the ring, or -1
+}
+
+// registerName returns the named register r refers to. An uppercase letter
+// refers to the same register as the lowercase one, to append to it
+func registerName(r rune) (name rune, append bool, ok bool) {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return r, false, true
+	case r >= 'A' && r <= 'Z':
+		return r - 'A' + 'a', true, true
+	}
+	return 0, false, false
+}
+
+// appendRegister returns the content of a followed by the one of b. Lines
+// added to anything make lines
+func appendRegister(a, b register) register {
+	switch {
+	case a.Text == "":
+		return b
+	case a.Kind == LineSelection || b.Kind == LineSelection || a.Kind == BlockSelection:
+		return register{Text: a.Text + "\n" + b.Text, Kind: max(a.Kind, b.Kind)}
+	}
+	return register{Text: a.Text + b.Text, Kind: a.Kind}
+}
+
+// the + and * registers are the system clipboard
+func isClipboardRegister(r rune) bool {
+	return r == '+' || r == '*'
+}
+
+// select the register the next command yanks to or pastes from: a named one,
+// one of the ring from 0, the most recent, to 9, " for the most recent one, or
+// + and * for the system clipboard
+func (e *Editor) selectRegister(r rune) {
+	if _, _, ok := registerName(r); !ok && r != '"' && (r < '0' || r > '9') && !isClipboardRegister(r) {
+		e.showError(fmt.Sprintf(str.InvalidRegisterErr, r))
+		return
+	}
+	e.register = r
+}
+
+// yank puts text at the front of the ring, and in the register selected for
+// the command if it is a named one or the system clipboard
+func (e *Editor) yank(text string, kind int) {
+	reg := register{Text: text, Kind: kind}
+	if isClipboardRegister(e.register) {
+		e.copyToClipboard(reg)
+	}
+	if name, appending, ok := registerName(e.register); ok {
+		if appending {
+			reg = appendRegister(e.registers[name], reg)
+		}
+		e.setRegister(name, reg)
+	}
+	e.ring = append([]register{reg}, e.ring[:min(len(e.ring), ringSize-1)]...)
+}
+
+// readRegister returns the content of the register selected for the command,
+// the most recent of the ring by default
+func (e *Editor) readRegister() (register, bool) {
+	r := e.register
+	if isClipboardRegister(r) {
+		return e.pasteFromClipboard()
+	}
+	if name, _, ok := registerName(r); ok {
+		reg, ok := e.registers[name]
+		return reg, ok
+	}
+
+	i := 0
+	if r >= '0' && r <= '9' {
+		i = int(r - '0')
+	}
+	if i >= len(e.ring) {
+		return register{}, false
+	}
+	return e.ring[i], true
+}
+
+// copy reg to the system clipboard. Lines end with a newline there
+func (e *Editor) copyToClipboard(reg register) {
+	text := reg.Text
+	if reg.Kind == LineSelection {
+		text += "\n"
+	}
+	if err := e.clipboard.Copy(text); err != nil {
+		e.showError(str.CannotCopyErr + err.Error())
+	}
+}
+
+// pasteFromClipboard returns the content of the system clipboard, which is
+// made of lines when it ends with a newline
+func (e *Editor) pasteFromClipboard() (register, bool) {
+	text, err := e.clipboard.Paste()
+	if err != nil {
+		e.showError(str.CannotPasteErr + err.Error())
+		return register{}, false
+	}
+	if lines, ok := strings.CutSuffix(text, "\n"); ok {
+		return register{Text: lines, Kind: LineSelection}, true
+	}
+	return register{Text: text, Kind: CharSelection}, true
+}
+
+// set the content of a named register, which is kept in the session file
+func (e *Editor) setRegister(name rune, reg register) {
+	if e.registers == nil {
+		e.registers = make(map[rune]register)
+	}
+	e.registers[name] = reg
+	e.saveSession()
+}
+
+// paste the content of the selected register, and remember it to be able to
+// cycle through the ring
+func (e *Editor) paste() {
+	reg, ok := e.readRegister()
+	if !ok || reg.Text == "" {
+		return
+	}
+
+	e.pasteRegister(reg)
+	e.pasted = &pasted{
+		document: e.Document,
+		seq:      e.History.Last() + 1,
+		index:    slices.Index(e.ring, reg),
+	}
+}
+
+// replace the text just pasted by the previous one of the ring
+func (e *Editor) cyclePaste() {
+	p := e.pasted
+	if p == nil || p.document != e.Document || p.seq != e.History.Current() || len(e.ring) == 0 {
+		e.showError(str.NothingPastedErr)
+		return
+	}
+
+	e.undo()
+	i := (p.index + 1) % len(e.ring)
+	e.pasteRegister(e.ring[i])
+	e.pasted = &pasted{document: e.Document, seq: e.History.Last() + 1, index: i}
+	e.showInfo(fmt.Sprintf(str.RingEntryMsg, i+1, len(e.ring)))
+}
+
+// registerLines returns the content of the ring and the named registers, as
+// listed by :registers
+func (e *Editor) registerLines() []string {
+	kinds := map[int]string{CharSelection: "c", LineSelection: "l", BlockSelection: "b"}
+	line := func(name rune, reg register) string {
+		text := strings.ReplaceAll(reg.Text, "\n", "^J")
+		text = strings.ReplaceAll(text, "\t", "^I")
+		if runes := []rune(text); len(runes) > e.Width-10 {
+			text = string(runes[:max(e.Width-10, 0)])
+		}
+		return fmt.Sprintf("  %s  \"%c   %s", kinds[reg.Kind], name, text)
+	}
+
+	lines := []string{str.RegistersHeader}
+	if len(e.ring) > 0 {
+		lines = append(lines, line('"', e.ring[0]))
+	}
+	for i, reg := range e.ring {
+		lines = append(lines, line(rune('0'+i), reg))
+	}
+	for name := 'a'; name <= 'z'; name++ {
+		if reg, ok := e.registers[name]; ok && reg.Text != "" {
+			lines = append(lines, line(name, reg))
+		}
+	}
+	return lines
+}
+
+// save the session file, with the named registers
+func (e *Editor) saveSession() {
+	s := state.Session{Registers: make(map[string]state.Register)}
+	for name, reg := range e.registers {
+		s.Registers[string(name)] = state.Register(reg)
+	}
+	if err := state.SaveSession(s); err != nil {
+		e.showError(str.CannotSaveSessionErr + err.Error())
+	}
+}
+
+// load the named registers from the session file
+func (e *Editor) loadSession() {
+	s, err := state.LoadSession()
+	if err != nil {
+		e.showError(str.CannotLoadSessionErr + err.Error())
+		return
+	}
+
+	e.registers = make(map[rune]register)
+	for name, reg := range s.Registers {
+		r, size := utf8.DecodeRuneInString(name)
+		if r, _, ok := registerName(r); ok && size == len(name) {
+			e.registers[r] = register(reg)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package editor
+
+import (
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// repeat keeps the last change made to a buffer as the keys typed to make it,
+// from the count or the selection to the end of the insert session, so it can
+// be made again wherever the cursor is with the . key. Commands typed on the
+// command line are not repeated
+type repeat struct {
+	keys    []tcell.Event // the keys of the command being typed
+	changed bool          // whether the command being typed changed a buffer
+	ex      bool          // whether the command being typed went through the command line
+	last    []tcell.Event // the keys of the last command that changed a buffer
+}
+
+// record a key typed by the user as part of the command being typed, and of
+// the macro being recorded if any
+func (e *Editor) recordKey(ev tcell.Event) {
+	if _, ok := ev.(*tcell.EventKey); !ok {
+		return
+	}
+	e.repeat.keys = append(e.repeat.keys, ev)
+	if e.recording != nil {
+		e.recording.keys = append(e.recording.keys, ev)
+	}
+}
+
+// end the command being typed once back in visual mode with nothing pending,
+// and keep it if it changed a buffer
+func (e *Editor) endCommand() {
+	if e.Mode != VisualMode || e.pending.active() || e.selecting() {
+		return
+	}
+	if e.repeat.changed && !e.repeat.ex && len(e.repeat.keys) > 0 {
+		e.repeat.last = e.repeat.keys
+	}
+	e.repeat.keys, e.repeat.changed, e.repeat.ex = nil, false, false
+}
+
+// make the last change again at the cursor. A count n other than 0 replaces
+// the count the change was made with, and a change whose command takes no
+// count is made n times
+func (e *Editor) repeatChange(n int) {
+	if n == 0 {
+		e.queue = append(e.queue, e.repeat.last...)
+	} else {
+		e.queue = append(e.queue, repeatKeys(e.repeat.last, n)...)
+	}
+	for len(e.queue) > 0 {
+		e.dispatch()
+	}
+
+	// the . key is not a change to repeat itself
+	e.repeat.keys = nil
+}
+
+// repeatKeys returns the keys of a change made with the count n instead of
+// the counts typed before and after its operator
+func repeatKeys(keys []tcell.Event, n int) []tcell.Event {
+	var register []tcell.Event
+	if len(keys) >= 2 && keyRune(keys[0]) == '"' {
+		register, keys = keys[:2], keys[2:]
+	}
+	keys = skipCount(keys)
+
+	op := 0
+	switch {
+	case len(keys) >= 2 && keyRune(keys[0]) == 'g' && keyRune(keys[1]) == 'c':
+		op = 2
+	case len(keys) >= 1 && (operators[string(keyRune(keys[0]))] || keyRune(keys[0]) == '@'):
+		op = 1
+	}
+	if op == 0 {
+		var repeated []tcell.Event
+		for range n {
+			repeated = append(append(repeated, register...), keys...)
+		}
+		return repeated
+	}
+
+	repeated := append([]tcell.Event{}, register...)
+	for _, r := range strconv.Itoa(n) {
+		repeated = append(repeated, tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	repeated = append(repeated, keys[:op]...)
+	return append(repeated, skipCount(keys[op:])...)
+}
+
+// skipCount returns keys without the count they begin with
+func skipCount(keys []tcell.Event) []tcell.Event {
+	for i, ev := range keys {
+		if r := keyRune(ev); r < '0' || r > '9' || i == 0 && r == '0' {
+			return keys[i:]
+		}
+	}
+	return nil
+}
+
+// keyRune returns the rune typed with ev, or 0 if it is not a rune key
+func keyRune(ev tcell.Event) rune {
+	if k, ok := ev.(*tcell.EventKey); ok && k.Key() == tcell.KeyRune {
+		return k.Rune()
+	}
+	return 0
+}
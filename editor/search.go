@@ -0,0 +1,260 @@
+package editor
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/eze-kiel/tide/str"
+	"github.com/gdamore/tcell/v2"
+)
+
+// search is a pattern looked for in the document, line by line
+type search struct {
+	pattern   string
+	re        *regexp.Regexp
+	backward  bool // whether the search goes toward the top of the document
+	highlight bool // whether the matches are highlighted
+}
+
+// incSearch is the search being typed, which moves the cursor to the first
+// match as the pattern is typed
+type incSearch struct {
+	backward bool
+	re       *regexp.Regexp // the pattern typed so far, nil while it is not valid
+
+	// where the search started, to go back there when it is cancelled
+	origin           cursor.Cursor
+	offsetX, offsetY int
+}
+
+// match is a part of a line matching the search, in runes, the end being
+// excluded
+type match struct {
+	start, end int
+}
+
+func (e *Editor) searchModeRoutine() {
+	ev := e.pollEvent()
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyEsc:
+			e.exitSearchMode()
+		case tcell.KeyEnter:
+			pattern, backward := e.CommandBuffer, e.incSearch.backward
+			e.exitSearchMode()
+			e.startSearch(pattern, backward)
+		default:
+			e.editCommandLine(ev)
+			if e.Mode == SearchMode {
+				e.updateIncSearch()
+			} else {
+				// the command line has been erased
+				e.exitSearchMode()
+			}
+		}
+	}
+}
+
+// start typing a search pattern
+func (e *Editor) enterSearchMode(backward bool) {
+	e.Mode = SearchMode
+	e.CommandBuffer = ""
+	e.CommandCursorPos = 0
+	e.incSearch = &incSearch{
+		backward: backward,
+		origin:   e.InternalCursor,
+		offsetX:  e.OffsetX,
+		offsetY:  e.OffsetY,
+	}
+}
+
+// stop typing the search pattern, going back to where the search started
+func (e *Editor) exitSearchMode() {
+	s := e.incSearch
+	e.incSearch = nil
+	e.InternalCursor = s.origin
+	e.OffsetX, e.OffsetY = s.offsetX, s.offsetY
+
+	e.exitCommandMode()
+}
+
+// move the cursor to the first match of the pattern typed so far
+func (e *Editor) updateIncSearch() {
+	s := e.incSearch
+	e.InternalCursor = s.origin
+	e.OffsetX, e.OffsetY = s.offsetX, s.offsetY
+
+	// the pattern is often invalid while it is being typed, it is only
+	// reported when the search is started
+	s.re = nil
+	if e.CommandBuffer != "" {
+		s.re, _ = regexp.Compile(e.CommandBuffer)
+	}
+	if s.re != nil {
+		if c, _, ok := e.findMatch(s.re, s.origin, s.backward); ok {
+			e.InternalCursor = c
+		}
+	}
+	e.moveInternalCursor(0, 0)
+}
+
+// the character the command line starts with
+func (e *Editor) commandPrompt() rune {
+	if e.Mode != SearchMode {
+		return ':'
+	}
+	return searchPrompt(e.incSearch.backward)
+}
+
+func searchPrompt(backward bool) rune {
+	if backward {
+		return '?'
+	}
+	return '/'
+}
+
+// search for pattern and go to the first match. An empty pattern searches for
+// the last one again
+func (e *Editor) startSearch(pattern string, backward bool) {
+	if e.search == nil {
+		e.search = &search{}
+	}
+	e.search.backward = backward
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			e.showError(str.InvalidPatternErr + err.Error())
+			return
+		}
+		e.search.pattern, e.search.re = pattern, re
+	}
+	e.searchNext(false)
+}
+
+// go to the next match of the search, or to the previous one when reverse is
+// set. The search wraps around the ends of the document
+func (e *Editor) searchNext(reverse bool) {
+	if e.search == nil || e.search.re == nil {
+		e.showError(str.NoPreviousSearchErr)
+		return
+	}
+
+	backward := e.search.backward != reverse
+	c, wrapped, ok := e.findMatch(e.search.re, e.InternalCursor, backward)
+	if !ok {
+		e.showError(str.PatternNotFoundErr + e.search.pattern)
+		return
+	}
+
+	e.InternalCursor = c
+	e.moveInternalCursor(0, 0)
+	e.search.highlight = true
+
+	i, n := e.matchIndex(c)
+	msg := fmt.Sprintf(str.SearchCountMsg, searchPrompt(e.search.backward), e.search.pattern, i, n)
+	if wrapped {
+		e.showWarning(str.SearchWrappedMsg + msg)
+	} else {
+		e.showInfo(msg)
+	}
+}
+
+// findMatch returns the position of the first match after from, or before it
+// when searching backward, and whether the search had to wrap around
+func (e *Editor) findMatch(re *regexp.Regexp, from cursor.Cursor, backward bool) (c cursor.Cursor, wrapped, ok bool) {
+	lines := e.InternalBuffer.LineCount()
+
+	// the line of the cursor is looked at twice, once for what is after the
+	// cursor and once for what is before it after wrapping around
+	for i := 0; i <= lines; i++ {
+		y, wrapped := from.Y+i, from.Y+i >= lines
+		if backward {
+			y, wrapped = from.Y-i, from.Y-i < 0
+		}
+		y = (y%lines + lines) % lines
+
+		matches := findMatches(re, e.InternalBuffer.Line(y))
+		if backward {
+			for j := len(matches) - 1; j >= 0; j-- {
+				if i == 0 && matches[j].start >= from.X {
+					continue
+				}
+				return cursor.Cursor{X: matches[j].start, Y: y}, wrapped, true
+			}
+			continue
+		}
+		for _, m := range matches {
+			if i == 0 && m.start <= from.X {
+				continue
+			}
+			return cursor.Cursor{X: m.start, Y: y}, wrapped, true
+		}
+	}
+	return from, false, false
+}
+
+// findMatches returns the matches of re in line, in runes
+func findMatches(re *regexp.Regexp, line string) []match {
+	var matches []match
+
+	// regexp works on bytes, so count the runes up to each match as they come
+	runes, last := 0, 0
+	for _, loc := range re.FindAllStringIndex(line, -1) {
+		runes += utf8.RuneCountInString(line[last:loc[0]])
+		start := runes
+		runes += utf8.RuneCountInString(line[loc[0]:loc[1]])
+		last = loc[1]
+		matches = append(matches, match{start: start, end: runes})
+	}
+	return matches
+}
+
+// matchIndex returns the number of the match at c, starting at 1, and the
+// number of matches in the document
+func (e *Editor) matchIndex(c cursor.Cursor) (i, n int) {
+	for y := range e.InternalBuffer.LineCount() {
+		for _, m := range findMatches(e.search.re, e.InternalBuffer.Line(y)) {
+			n++
+			if y < c.Y || (y == c.Y && m.start <= c.X) {
+				i = n
+			}
+		}
+	}
+	return i, n
+}
+
+// stop highlighting the matches of the last search, until the next search
+func (e *Editor) clearSearchHighlight() {
+	if e.search != nil {
+		e.search.highlight = false
+	}
+}
+
+// highlightedMatches returns the matches to highlight on line y of w, which
+// are the ones of the search being typed if any
+func (e *Editor) highlightedMatches(w *Window, y int) []match {
+	switch {
+	case e.incSearch != nil:
+		if e.incSearch.re == nil {
+			return nil
+		}
+		return findMatches(e.incSearch.re, w.InternalBuffer.Line(y))
+	case e.search == nil || e.search.re == nil || !e.search.highlight:
+		return nil
+	}
+	return findMatches(e.search.re, w.InternalBuffer.Line(y))
+}
+
+// whether the x-th rune is part of one of the matches
+func inMatch(matches []match, x int) bool {
+	for _, m := range matches {
+		if x >= m.start && x < m.end {
+			return true
+		}
+	}
+	return false
+}
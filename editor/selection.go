@@ -0,0 +1,321 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/eze-kiel/tide/str"
+)
+
+const (
+	NoSelection = iota
+	CharSelection
+	LineSelection
+	BlockSelection
+)
+
+// Selection is the text selected in a window. It goes from the anchor to the
+// cursor, both included, so any motion of the cursor extends it
+type Selection struct {
+	Kind   int
+	Anchor cursor.Cursor
+}
+
+func (w *Window) selecting() bool {
+	return w.Selection.Kind != NoSelection
+}
+
+// selectionBounds returns the first and the last selected positions
+func (w *Window) selectionBounds() (first, last cursor.Cursor) {
+	first, last = w.Selection.Anchor, w.InternalCursor
+	if last.Y < first.Y || (last.Y == first.Y && last.X < first.X) {
+		first, last = last, first
+	}
+	return first, last
+}
+
+// selectionRange returns the offsets of the selected text, the end being
+// excluded. Line selections do not include the last newline
+func (w *Window) selectionRange() (start, end int) {
+	first, last := w.selectionBounds()
+	b := w.InternalBuffer
+
+	if w.Selection.Kind == LineSelection {
+		return b.Offset(0, first.Y), b.Offset(b.LineLen(last.Y), last.Y)
+	}
+	return b.Offset(first.X, first.Y), min(b.Offset(last.X, last.Y)+1, b.Len())
+}
+
+// selectedRunes returns the runes of line y covered by the selection, the end
+// being excluded. It goes past the end of the line when the newline is
+// selected
+func (w *Window) selectedRunes(y int) (start, end int, ok bool) {
+	if !w.selecting() {
+		return 0, 0, false
+	}
+	first, last := w.selectionBounds()
+	if y < first.Y || y > last.Y {
+		return 0, 0, false
+	}
+
+	end = w.InternalBuffer.LineLen(y) + 1
+	switch w.Selection.Kind {
+	case LineSelection:
+		return 0, end, true
+	case BlockSelection:
+		left, right := w.blockColumns()
+		start, end = columnsToRunes([]rune(w.InternalBuffer.Line(y)), left, right)
+		return start, end, true
+	}
+	if y == first.Y {
+		start = first.X
+	}
+	if y == last.Y {
+		end = last.X + 1
+	}
+	return start, end, true
+}
+
+// selectedLines returns the first and last lines covered by the selection, or
+// the current line when nothing is selected
+func (w *Window) selectedLines() (first, last int) {
+	if !w.selecting() {
+		return w.InternalCursor.Y, w.InternalCursor.Y
+	}
+	f, l := w.selectionBounds()
+	return f.Y, l.Y
+}
+
+// start a selection of the given kind at the cursor, or stop it if it is
+// already going
+func (e *Editor) toggleSelection(kind int) {
+	switch {
+	case e.Selection.Kind == kind:
+		e.cancelSelection()
+	case e.selecting():
+		// keep the anchor when going from one kind to another
+		e.Selection.Kind = kind
+	default:
+		e.Selection = Selection{Kind: kind, Anchor: e.InternalCursor}
+	}
+}
+
+// select the current line, or extend the line selection to the next line
+func (e *Editor) selectLine() {
+	if e.Selection.Kind == LineSelection {
+		e.moveInternalCursor(0, 1)
+		return
+	}
+	e.Selection = Selection{Kind: LineSelection, Anchor: e.InternalCursor}
+}
+
+// stop selecting, and remember the selected lines for the '< and '> addresses
+func (e *Editor) cancelSelection() {
+	if e.selecting() {
+		first, last := e.selectedLines()
+		e.lastSelection = lineRange{first: first, last: last, given: true}
+	}
+	e.Selection = Selection{}
+}
+
+func (e *Editor) copySelection() {
+	if !e.selecting() {
+		return
+	}
+
+	first, _ := e.selectionBounds()
+	e.yankSelection()
+	if e.Selection.Kind == BlockSelection {
+		left, _ := e.blockColumns()
+		first.X = columnToRune([]rune(e.InternalBuffer.Line(first.Y)), left)
+	}
+
+	e.cancelSelection()
+	e.InternalCursor = first
+	e.moveInternalCursor(0, 0)
+}
+
+// put the selected text in the registers
+func (e *Editor) yankSelection() {
+	if e.Selection.Kind == BlockSelection {
+		e.yank(e.selectedBlockText(), BlockSelection)
+		return
+	}
+	start, end := e.selectionRange()
+	e.yank(e.InternalBuffer.Slice(start, end), e.Selection.Kind)
+}
+
+// delete the selection, and put it in the registers
+func (e *Editor) cutSelection() {
+	if !e.selecting() {
+		return
+	}
+	e.yankSelection()
+	e.deleteSelection()
+}
+
+func (e *Editor) deleteSelection() {
+	if !e.selecting() {
+		return
+	}
+	if e.Selection.Kind == BlockSelection {
+		e.deleteBlock()
+		return
+	}
+
+	first, last := e.selectionBounds()
+	start, end := e.selectionRange()
+
+	// deleting whole lines also takes one of the newlines around them away
+	if e.Selection.Kind == LineSelection {
+		if last.Y+1 < e.InternalBuffer.LineCount() {
+			end++
+		} else if first.Y > 0 {
+			start--
+		}
+		first.X = 0
+	}
+
+	x, y := e.InternalBuffer.Pos(start)
+	e.deleteText(x, y, end-start)
+	e.cancelSelection()
+
+	e.InternalCursor = first
+	e.moveInternalCursor(0, 0)
+}
+
+// paste reg. Whole lines go under the current line and anything else after
+// the cursor, unless some text is selected, in which case it gets replaced
+func (e *Editor) pasteRegister(reg register) {
+	switch {
+	case e.selecting():
+		e.replaceSelection(reg)
+	case reg.Kind == LineSelection:
+		e.pasteUnder(reg.Text)
+	case reg.Kind == BlockSelection:
+		lineRunes := []rune(e.InternalBuffer.Line(e.InternalCursor.Y))
+		x := min(e.InternalCursor.X+1, len(lineRunes))
+		e.pasteBlock(reg.Text, renderColumn(lineRunes, x), e.InternalCursor.Y)
+	default:
+		e.pasteAfter(reg.Text)
+	}
+}
+
+func (e *Editor) pasteUnder(text string) {
+	y := e.InternalCursor.Y
+
+	e.insertText(e.InternalBuffer.LineLen(y), y, "\n"+text)
+
+	e.InternalCursor.X = 0
+	e.InternalCursor.Y = y + 1
+	e.updateRenderCursor()
+}
+
+func (e *Editor) pasteAfter(text string) {
+	y := e.InternalCursor.Y
+	x := min(e.InternalCursor.X+1, e.InternalBuffer.LineLen(y))
+
+	e.insertText(x, y, text)
+
+	// end up on the last pasted rune
+	off := e.InternalBuffer.Offset(x, y) + buffer.RuneLength(text) - 1
+	e.InternalCursor.X, e.InternalCursor.Y = e.InternalBuffer.Pos(off)
+	e.updateRenderCursor()
+}
+
+func (e *Editor) replaceSelection(reg register) {
+	// blocks are pasted where the deleted text was
+	if e.Selection.Kind == BlockSelection || reg.Kind == BlockSelection {
+		e.deleteSelection()
+		if reg.Kind == BlockSelection {
+			lineRunes := []rune(e.InternalBuffer.Line(e.InternalCursor.Y))
+			e.pasteBlock(reg.Text, renderColumn(lineRunes, e.InternalCursor.X), e.InternalCursor.Y)
+		} else {
+			e.insertText(e.InternalCursor.X, e.InternalCursor.Y, reg.Text)
+		}
+		return
+	}
+
+	first, _ := e.selectionBounds()
+	start, end := e.selectionRange()
+
+	x, y := e.InternalBuffer.Pos(start)
+	e.deleteText(x, y, end-start)
+	e.insertText(x, y, reg.Text)
+	e.cancelSelection()
+
+	e.InternalCursor = first
+	e.moveInternalCursor(0, 0)
+}
+
+// a line is commented if its first word starts with a comment
+func isCommented(line string) bool {
+	parts := strings.Fields(line)
+	return len(parts) > 0 && strings.Contains(parts[0], str.Comment)
+}
+
+// toggle the comment on the selected lines, or the current one when nothing
+// is selected
+func (e *Editor) toggleComment() {
+	first, last := e.selectedLines()
+	e.commentLines(first, last)
+
+	if !e.selecting() {
+		e.InternalCursor.X = buffer.RuneLength(e.InternalBuffer.Line(first))
+	}
+	e.moveInternalCursor(0, 0)
+}
+
+// toggle the comment on the lines from first to last. The lines are
+// uncommented only if all of them are commented
+func (e *Editor) commentLines(first, last int) {
+	// todo: use specific comment based on the file extension if known,
+	// otherwise go to default
+
+	uncomment := true
+	for y := first; y <= last; y++ {
+		line := e.InternalBuffer.Line(y)
+		if !isCommented(line) && (first == last || strings.TrimSpace(line) != "") {
+			uncomment = false
+		}
+	}
+
+	for y := first; y <= last; y++ {
+		line := e.InternalBuffer.Line(y)
+		if uncomment {
+			if idx := strings.Index(line, str.Comment+" "); idx >= 0 {
+				e.deleteText(buffer.RuneLength(line[:idx]), y, buffer.RuneLength(str.Comment+" "))
+			}
+		} else if first == last || strings.TrimSpace(line) != "" {
+			e.insertText(0, y, str.Comment+" ")
+		}
+	}
+}
+
+// indent the lines from first to last by one level. A negative level removes
+// one level of indentation
+func (e *Editor) indentLines(first, last, level int) {
+	for y := first; y <= last; y++ {
+		line := e.InternalBuffer.Line(y)
+		if level > 0 {
+			if line != "" {
+				e.insertText(0, y, "\t")
+			}
+			continue
+		}
+
+		// remove a tab, or as many spaces as a tab is wide
+		n := 0
+		if strings.HasPrefix(line, "\t") {
+			n = 1
+		} else {
+			for n < buffer.TAB_SIZE && n < len(line) && line[n] == ' ' {
+				n++
+			}
+		}
+		e.deleteText(0, y, n)
+	}
+
+	e.moveInternalCursor(0, 0)
+}
@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/eze-kiel/tide/statusline"
+	"github.com/eze-kiel/tide/str"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// what the segments of the status line show about the current window
+func (e *Editor) statusInfo() statusline.Info {
+	b := e.InternalBuffer
+	lineRunes := []rune(b.Line(e.InternalCursor.Y))
+	info := statusline.Info{
+		Mode:      str.VisualMode,
+		File:      e.name(),
+		Modified:  e.fileChanged,
+		Line:      e.InternalCursor.Y + 1,
+		Lines:     b.LineCount(),
+		Column:    e.InternalCursor.X + 1,
+		Display:   renderColumn(lineRunes, e.InternalCursor.X) + 1,
+		FileType:  e.fileType(),
+		Encoding:  "utf-8",
+		Selection: e.selectionSize(),
+	}
+	if e.Mode == EditMode {
+		info.Mode = str.EditMode
+	}
+	if e.recording != nil {
+		info.Recording = fmt.Sprintf(str.RecordingMsg, e.recording.register)
+	}
+
+	// the line ending and the byte order mark are the ones of the beginning of
+	// the file, which are kept when it is saved
+	info.LineEnding = "lf"
+	if strings.HasSuffix(b.Line(0), "\r") {
+		info.LineEnding = "crlf"
+	}
+	if b.Len() > 0 && b.RuneAt(0) == '\uFEFF' {
+		info.Encoding = "utf-8-bom"
+	}
+	return info
+}
+
+// the type of the file, being the name of its grammar or else its extension
+func (d *Document) fileType() string {
+	d.highlight(0)
+	if name := d.highlighter.Name(); name != "" {
+		return name
+	}
+	return strings.TrimPrefix(filepath.Ext(d.Filename), ".")
+}
+
+// how much text is selected, if any
+func (w *Window) selectionSize() string {
+	first, last := w.selectionBounds()
+	switch w.Selection.Kind {
+	case LineSelection:
+		return fmt.Sprintf(str.SelectedLinesMsg, last.Y-first.Y+1)
+	case BlockSelection:
+		left, right := w.blockColumns()
+		return fmt.Sprintf(str.SelectedBlockMsg, last.Y-first.Y+1, right-left)
+	case CharSelection:
+		b := w.InternalBuffer
+		end := min(b.Offset(last.X, last.Y)+1, b.Len())
+		return fmt.Sprintf(str.SelectedCharsMsg, end-b.Offset(first.X, first.Y))
+	}
+	return ""
+}
+
+// draw the status line at the bottom of the screen. The status message, when
+// there is one, is shown instead of the segments aligned on the right, over
+// the left ones when it is too long
+func (e *Editor) drawStatusLine() {
+	left, right := e.statusLine.Render(e.statusInfo())
+
+	x := 0
+	for i, part := range left {
+		if i > 0 {
+			x++
+		}
+		style := e.theme.CommandLine
+		if part.Name == "mode" {
+			style = e.theme.StatusBar
+		}
+		x = e.drawStatusText(x, part.Text, style)
+	}
+
+	var texts []string
+	for _, part := range right {
+		texts = append(texts, part.Text)
+	}
+	msg, style := strings.Join(texts, " "), e.theme.CommandLine
+	if m, ok := e.statusMessage(); ok {
+		msg, style = m.text, e.messageStyle(m.severity)
+	}
+	e.drawStatusText(max(0, e.Width-runewidth.StringWidth(msg)), msg, style)
+}
+
+// draw text on the status line from the x column, and return the column
+// following it
+func (e *Editor) drawStatusText(x int, text string, style tcell.Style) int {
+	for _, r := range text {
+		if x >= e.Width {
+			break
+		}
+		e.Screen.SetContent(x, e.Height-1, r, nil, style)
+		x += runewidth.RuneWidth(r)
+	}
+	return x
+}
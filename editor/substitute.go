@@ -0,0 +1,214 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/eze-kiel/tide/str"
+	"github.com/gdamore/tcell/v2"
+)
+
+// substitution is a parsed :s command
+type substitution struct {
+	re          *regexp.Regexp
+	replacement string // may reference the groups of re, like $1 or ${name}
+	global      bool   // replace every match of a line instead of the first one
+	confirm     bool   // ask before each replacement
+}
+
+// isSubstitute tells if cmd, without its range, is a :s command. The s must be
+// followed by the delimiter, so commands like :sp are not mistaken for it
+func isSubstitute(cmd string) bool {
+	if !strings.HasPrefix(cmd, "s") || len(cmd) < 2 {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(cmd[1:])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) && r != '\\'
+}
+
+// parseSubstitute parses the /re/replacement/flags part of a :s command. The
+// delimiter is the first character, and can be escaped with a backslash in the
+// pattern and the replacement. An empty pattern is the one of the last search
+func (e *Editor) parseSubstitute(args string) (substitution, error) {
+	delim, size := utf8.DecodeRuneInString(args)
+	parts := splitEscaped(args[size:], delim)
+
+	var s substitution
+	pattern := parts[0]
+	if len(parts) > 1 {
+		s.replacement = parts[1]
+	}
+
+	ignoreCase := false
+	if len(parts) > 2 {
+		for _, f := range parts[2] {
+			switch f {
+			case 'g':
+				s.global = true
+			case 'i':
+				ignoreCase = true
+			case 'c':
+				s.confirm = true
+			default:
+				return s, fmt.Errorf(str.InvalidFlagErr, f)
+			}
+		}
+	}
+
+	if pattern == "" {
+		if e.search == nil || e.search.re == nil {
+			return s, errors.New(str.NoPreviousSearchErr)
+		}
+		pattern = e.search.pattern
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	var err error
+	s.re, err = regexp.Compile(pattern)
+	if err != nil {
+		return s, fmt.Errorf("%s%w", str.InvalidPatternErr, err)
+	}
+	return s, nil
+}
+
+// splitEscaped splits s around the unescaped delimiters, in at most 3 parts.
+// Escaped delimiters lose their backslash, other escapes are kept for the
+// regexp
+func splitEscaped(s string, delim rune) []string {
+	var parts []string
+	var sb strings.Builder
+
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r != delim {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == delim && len(parts) < 2:
+			parts = append(parts, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if escaped {
+		sb.WriteRune('\\')
+	}
+	return append(parts, sb.String())
+}
+
+// substitute runs a :s command on the lines of r, and reports how many
+// replacements have been made. Being a single command, it is undone at once
+func (e *Editor) substitute(r lineRange, args string) {
+	s, err := e.parseSubstitute(args)
+	if err != nil {
+		e.showError(err.Error())
+		return
+	}
+
+	// leave the command line so the document can be looked at when confirming
+	e.exitCommandMode()
+
+	found, count, lines, last := false, 0, 0, -1
+	ask := s.confirm
+loop:
+	for y := r.first; y <= r.last; y++ {
+		line := e.InternalBuffer.Line(y)
+		matches := s.re.FindAllStringSubmatchIndex(line, -1)
+		if !s.global && len(matches) > 1 {
+			matches = matches[:1]
+		}
+
+		// the runes replaced so far on the line shift the next matches
+		shift := 0
+		for _, m := range matches {
+			found = true
+			start := utf8.RuneCountInString(line[:m[0]]) + shift
+			n := utf8.RuneCountInString(line[m[0]:m[1]])
+			repl := string(s.re.ExpandString(nil, s.replacement, line, m))
+
+			key := 'y'
+			if ask {
+				key = e.confirmReplacement(cursor.Cursor{X: start, Y: y}, n, repl)
+			}
+			switch key {
+			case 'n':
+				continue
+			case 'q':
+				break loop
+			case 'a':
+				ask = false
+			}
+
+			e.replaceText(start, y, n, repl)
+			shift += buffer.RuneLength(repl) - n
+			count++
+			if y != last {
+				lines++
+				last = y
+			}
+			if key == 'l' {
+				break loop
+			}
+		}
+	}
+
+	if !found {
+		e.showError(str.PatternNotFoundErr + s.re.String())
+		return
+	}
+	if count > 0 {
+		e.InternalCursor = cursor.Cursor{X: 0, Y: last}
+		e.moveInternalCursor(0, 0)
+	}
+	e.showInfo(fmt.Sprintf(str.SubstituteMsg, count, lines))
+}
+
+// replace the n runes at the (x, y) position by s
+func (e *Editor) replaceText(x, y, n int, s string) {
+	e.deleteText(x, y, n)
+	e.insertText(x, y, s)
+}
+
+// show the match of n runes at c and ask whether to replace it with repl. It
+// returns the key that has been hit: y, n, a (all), l (last) or q (quit)
+func (e *Editor) confirmReplacement(c cursor.Cursor, n int, repl string) rune {
+	e.InternalCursor = c
+	e.moveInternalCursor(0, 0)
+	if n > 0 {
+		e.Selection = Selection{Kind: CharSelection, Anchor: c}
+		e.InternalCursor.X += n - 1
+	}
+	// not a selection made by the user, so the '< and '> marks are kept
+	defer func() { e.Selection = Selection{} }()
+
+	for {
+		e.showPrompt(fmt.Sprintf(str.ConfirmReplaceMsg, repl))
+		e.render()
+
+		ev, ok := e.pollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch {
+		case ev.Key() == tcell.KeyEsc:
+			return 'q'
+		case ev.Key() == tcell.KeyRune && strings.ContainsRune("ynalq", ev.Rune()):
+			e.InternalCursor = c
+			return ev.Rune()
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+)
+
+// textObject returns the text around c an operator or a selection applies to,
+// as offsets of b, the end being excluded. The inner object (around == false)
+// leaves out the delimiters and blanks that the outer one includes. Objects
+// made of whole lines are linewise
+type textObject func(b buffer.Buffer, c cursor.Cursor, around bool) (start, end int, kind int, ok bool)
+
+// textObjectKey returns the text object bound to the key typed after i or a
+func textObjectKey(r rune) (textObject, bool) {
+	switch r {
+	case 'w':
+		return wordObject(false), true
+	case 'W':
+		return wordObject(true), true
+	case '"', '\'', '`':
+		return quoteObject(r), true
+	case '(', ')', 'b':
+		return bracketObject('(', ')'), true
+	case '[', ']':
+		return bracketObject('[', ']'), true
+	case '{', '}', 'B':
+		return bracketObject('{', '}'), true
+	case '<', '>':
+		return bracketObject('<', '>'), true
+	case 'p':
+		return paragraphObject, true
+	}
+	return nil, false
+}
+
+// wordObject is the word the cursor is in, or the blanks between two words.
+// The outer word includes the blanks after it, or before it when there are
+// none after
+func wordObject(big bool) textObject {
+	return func(b buffer.Buffer, c cursor.Cursor, around bool) (int, int, int, bool) {
+		line := []rune(b.Line(c.Y))
+		if len(line) == 0 {
+			return 0, 0, 0, false
+		}
+		x := min(c.X, len(line)-1)
+
+		class := runeClass(line[x], big)
+		start, end := x, x+1
+		for start > 0 && runeClass(line[start-1], big) == class {
+			start--
+		}
+		for end < len(line) && runeClass(line[end], big) == class {
+			end++
+		}
+
+		if around && class != blankClass {
+			after := end
+			for after < len(line) && runeClass(line[after], big) == blankClass {
+				after++
+			}
+			if after > end {
+				end = after
+			} else {
+				for start > 0 && runeClass(line[start-1], big) == blankClass {
+					start--
+				}
+			}
+		}
+
+		off := b.Offset(0, c.Y)
+		return off + start, off + end, exclusive, true
+	}
+}
+
+// quoteObject is the string the cursor is in, or the first one after it on the
+// line. The quotes are paired from the beginning of the line, escaped ones
+// being skipped
+func quoteObject(quote rune) textObject {
+	return func(b buffer.Buffer, c cursor.Cursor, around bool) (int, int, int, bool) {
+		line := []rune(b.Line(c.Y))
+
+		var quotes []int
+		for x := 0; x < len(line); x++ {
+			switch line[x] {
+			case '\\':
+				x++
+			case quote:
+				quotes = append(quotes, x)
+			}
+		}
+
+		for i := 0; i+1 < len(quotes); i += 2 {
+			open, close := quotes[i], quotes[i+1]
+			if close < c.X {
+				continue
+			}
+
+			off := b.Offset(0, c.Y)
+			if around {
+				return off + open, off + close + 1, exclusive, true
+			}
+			return off + open + 1, off + close, exclusive, true
+		}
+		return 0, 0, 0, false
+	}
+}
+
+// bracketObject is the text between the open and close brackets the cursor is
+// in, which can span several lines. When the brackets are on lines of their
+// own, the inner object is made of the lines between them
+func bracketObject(open, close rune) textObject {
+	return func(b buffer.Buffer, c cursor.Cursor, around bool) (int, int, int, bool) {
+		off := b.Offset(c.X, c.Y)
+
+		// look backward for the open bracket, skipping the pairs in between.
+		// The cursor can be on the close bracket
+		start, depth := -1, 0
+		if off < b.Len() && b.RuneAt(off) == close {
+			off--
+		}
+		for o := off; o >= 0; o-- {
+			switch b.RuneAt(o) {
+			case close:
+				depth++
+			case open:
+				if depth == 0 {
+					start = o
+				}
+				depth--
+			}
+			if start >= 0 {
+				break
+			}
+		}
+		if start < 0 {
+			return 0, 0, 0, false
+		}
+
+		end := -1
+		depth = 0
+		for o := start + 1; o < b.Len() && end < 0; o++ {
+			switch b.RuneAt(o) {
+			case open:
+				depth++
+			case close:
+				if depth == 0 {
+					end = o
+				}
+				depth--
+			}
+		}
+		if end < 0 {
+			return 0, 0, 0, false
+		}
+
+		if around {
+			return start, end + 1, exclusive, true
+		}
+
+		start++
+		if start < end && b.RuneAt(start) == '\n' {
+			start++
+
+			// keep the indentation of the close bracket
+			_, y := b.Pos(end)
+			if strings.TrimLeft(b.Slice(b.Offset(0, y), end), " \t") == "" {
+				end = b.Offset(0, y)
+			}
+		}
+		return start, max(start, end), exclusive, true
+	}
+}
+
+// paragraphObject is the paragraph the cursor is in, or the blank lines
+// between two paragraphs. The outer paragraph includes the blank lines after
+// it, or before it when there are none after
+func paragraphObject(b buffer.Buffer, c cursor.Cursor, around bool) (int, int, int, bool) {
+	blank := func(y int) bool { return strings.TrimSpace(b.Line(y)) == "" }
+
+	lines := b.LineCount()
+	first, last := c.Y, c.Y
+	kind := blank(c.Y)
+	for first > 0 && blank(first-1) == kind {
+		first--
+	}
+	for last+1 < lines && blank(last+1) == kind {
+		last++
+	}
+
+	if around && !kind {
+		after := last
+		for after+1 < lines && blank(after+1) {
+			after++
+		}
+		if after > last {
+			last = after
+		} else {
+			for first > 0 && blank(first-1) {
+				first--
+			}
+		}
+	}
+
+	return b.Offset(0, first), b.Offset(b.LineLen(last), last), linewise, true
+}
+
+// select the text object around the cursor
+func (e *Editor) selectTextObject(obj textObject, around bool) {
+	b := e.InternalBuffer
+	start, end, kind, ok := obj(b, e.InternalCursor, around)
+	switch {
+	case !ok:
+		return
+	case kind == linewise:
+		_, last := b.Pos(end)
+		e.Selection = Selection{Kind: LineSelection, Anchor: position(b, start)}
+		e.InternalCursor = cursor.Cursor{X: 0, Y: last}
+	case end > start:
+		e.Selection = Selection{Kind: CharSelection, Anchor: position(b, start)}
+		e.InternalCursor = position(b, end-1)
+	}
+	e.moveInternalCursor(0, 0)
+}
+
+// apply the operator to the text object around the cursor
+func (e *Editor) operateTextObject(op string, obj textObject, around bool) {
+	start, end, kind, ok := obj(e.InternalBuffer, e.InternalCursor, around)
+	if !ok {
+		return
+	}
+	if kind == linewise {
+		_, first := e.InternalBuffer.Pos(start)
+		_, last := e.InternalBuffer.Pos(end)
+		e.operateLines(op, lineRange{first: first, last: last})
+		return
+	}
+	e.operateText(op, start, end)
+}
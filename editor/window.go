@@ -0,0 +1,485 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/eze-kiel/tide/buffer"
+	"github.com/eze-kiel/tide/cursor"
+	"github.com/eze-kiel/tide/str"
+	"github.com/eze-kiel/tide/syntax"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// Window is a view on a document. Several windows can show the same document,
+// each one with its own cursor and scrolling
+type Window struct {
+	*Document
+
+	OffsetX, OffsetY int
+
+	InternalCursor, RenderCursor cursor.Cursor
+	Cursors                      []cursor.Cursor // the other cursors edits are made at
+
+	Selection Selection
+
+	area rect // where the window is drawn, its last row being the window bar
+}
+
+type rect struct {
+	x, y, w, h int
+}
+
+func newWindow(d *Document) *Window {
+	w := &Window{}
+	w.load(d)
+	return w
+}
+
+// load shows d in the window, with the cursor where it was left
+func (w *Window) load(d *Document) {
+	w.Document = d
+	w.InternalCursor = d.InternalCursor
+	w.Cursors = nil
+	w.OffsetX, w.OffsetY = d.OffsetX, d.OffsetY
+}
+
+// save remembers where the cursor is in the document, for the next time it is
+// loaded in a window
+func (w *Window) save() {
+	w.Document.InternalCursor = w.InternalCursor
+	w.Document.OffsetX, w.Document.OffsetY = w.OffsetX, w.OffsetY
+}
+
+// number of lines of the document the window can show
+func (w *Window) textHeight() int {
+	return max(0, w.area.h-1)
+}
+
+// number of columns of the document the window can show
+func (w *Window) textWidth() int {
+	return max(0, w.area.w-LineNumberWidth)
+}
+
+// layout is a tree describing how the windows share the screen. Leaves hold a
+// window, and inner nodes split their area between two children
+type layout struct {
+	window *Window
+
+	first, second *layout
+	parent        *layout
+	vertical      bool    // children are side by side instead of stacked
+	ratio         float64 // share of the area given to the first child
+
+	area rect
+}
+
+// windows returns the windows of the layout, from top-left to bottom-right
+func (l *layout) windows() []*Window {
+	if l.window != nil {
+		return []*Window{l.window}
+	}
+	return append(l.first.windows(), l.second.windows()...)
+}
+
+// find returns the leaf holding w
+func (l *layout) find(w *Window) *layout {
+	if l.window != nil {
+		if l.window == w {
+			return l
+		}
+		return nil
+	}
+	if found := l.first.find(w); found != nil {
+		return found
+	}
+	return l.second.find(w)
+}
+
+// place computes the area of every window of the layout
+func (l *layout) place(r rect) {
+	l.area = r
+	if l.window != nil {
+		l.window.area = r
+		return
+	}
+
+	if l.vertical {
+		// keep a column for the separator
+		avail := r.w - 1
+		w := clampSize(l.ratio, avail)
+		l.first.place(rect{r.x, r.y, w, r.h})
+		l.second.place(rect{r.x + w + 1, r.y, avail - w, r.h})
+		return
+	}
+
+	h := clampSize(l.ratio, r.h)
+	l.first.place(rect{r.x, r.y, r.w, h})
+	l.second.place(rect{r.x, r.y + h, r.w, r.h - h})
+}
+
+// size of the first child of a split, leaving at least 2 cells to each child
+func clampSize(ratio float64, total int) int {
+	size := int(math.Round(ratio * float64(total)))
+	return max(min(size, total-2), min(2, total))
+}
+
+// split cuts the area of w in two, and gives the second half to nw
+func (l *layout) split(w, nw *Window, vertical bool) {
+	leaf := l.find(w)
+	if leaf == nil {
+		return
+	}
+
+	leaf.first = &layout{window: w, parent: leaf}
+	leaf.second = &layout{window: nw, parent: leaf}
+	leaf.window = nil
+	leaf.vertical = vertical
+	leaf.ratio = 0.5
+}
+
+// remove takes w out of the layout, its sibling taking its space, and returns
+// the node that took it. The root always keeps at least one window
+func (l *layout) remove(w *Window) *layout {
+	leaf := l.find(w)
+	if leaf == nil || leaf.parent == nil {
+		return leaf
+	}
+
+	parent := leaf.parent
+	sibling := parent.first
+	if sibling == leaf {
+		sibling = parent.second
+	}
+
+	// the parent becomes the sibling
+	*parent = layout{
+		window:   sibling.window,
+		first:    sibling.first,
+		second:   sibling.second,
+		parent:   parent.parent,
+		vertical: sibling.vertical,
+		ratio:    sibling.ratio,
+	}
+	if parent.first != nil {
+		parent.first.parent = parent
+		parent.second.parent = parent
+	}
+	return parent
+}
+
+// resize grows the window w by delta cells, in height or in width
+func (l *layout) resize(w *Window, delta int, vertical bool) {
+	node := l.find(w)
+	for node != nil && node.parent != nil {
+		parent := node.parent
+		if parent.vertical != vertical {
+			node = parent
+			continue
+		}
+
+		total := parent.area.h
+		size := parent.first.area.h
+		if vertical {
+			total = parent.area.w - 1
+			size = parent.first.area.w
+		}
+		if total <= 0 {
+			return
+		}
+
+		if parent.first == node {
+			size += delta
+		} else {
+			size -= delta
+		}
+		parent.ratio = float64(size) / float64(total)
+		parent.ratio = max(0, min(1, parent.ratio))
+		return
+	}
+}
+
+// relayout computes where each window goes on the screen, the last line
+// being kept for the status line
+func (e *Editor) relayout() {
+	e.layout.place(rect{0, 0, e.Width, e.Height - 1})
+}
+
+// windowAt returns the window drawn at the (x, y) position on the screen
+func (e *Editor) windowAt(x, y int) *Window {
+	for _, w := range e.layout.windows() {
+		if x >= w.area.x && x < w.area.x+w.area.w && y >= w.area.y && y < w.area.y+w.area.h {
+			return w
+		}
+	}
+	return nil
+}
+
+// give the focus to w
+func (e *Editor) focusWindow(w *Window) {
+	if w == nil || w == e.Window {
+		return
+	}
+	e.History.Commit()
+	e.cancelSelection()
+	e.Window = w
+	e.moveInternalCursor(0, 0)
+}
+
+// give the focus to the neighbour window in the (dx, dy) direction
+func (e *Editor) focusNeighbour(dx, dy int) {
+	// start from the cursor position on the screen, and look just past the
+	// window border and the separator
+	x := e.area.x + LineNumberWidth + e.RenderCursor.X - e.OffsetX
+	y := e.area.y + e.RenderCursor.Y - e.OffsetY
+	switch {
+	case dx < 0:
+		x = e.area.x - 2
+	case dx > 0:
+		x = e.area.x + e.area.w + 1
+	case dy < 0:
+		y = e.area.y - 1
+	case dy > 0:
+		y = e.area.y + e.area.h
+	}
+	e.focusWindow(e.windowAt(x, y))
+}
+
+// give the focus to the next window, n being negative to go backward
+func (e *Editor) cycleWindow(n int) {
+	windows := e.layout.windows()
+	for i, w := range windows {
+		if w == e.Window {
+			i = ((i+n)%len(windows) + len(windows)) % len(windows)
+			e.focusWindow(windows[i])
+			return
+		}
+	}
+}
+
+// split the current window, the new one showing the same document and getting
+// the focus
+func (e *Editor) splitWindow(vertical bool) {
+	nw := &Window{
+		Document:       e.Document,
+		InternalCursor: e.InternalCursor,
+		OffsetX:        e.OffsetX,
+		OffsetY:        e.OffsetY,
+	}
+	e.layout.split(e.Window, nw, vertical)
+	e.relayout()
+	e.focusWindow(nw)
+}
+
+// close the current window, unless it is the last one
+func (e *Editor) closeWindow() {
+	windows := e.layout.windows()
+	if len(windows) == 1 {
+		e.showError(str.LastWindowErr)
+		return
+	}
+
+	e.History.Commit()
+	e.cancelSelection()
+	e.Window.save()
+	next := e.layout.remove(e.Window)
+	e.relayout()
+
+	// go to the window that took the space
+	e.Window = next.windows()[0]
+	e.moveInternalCursor(0, 0)
+}
+
+// close every window but the current one
+func (e *Editor) onlyWindow() {
+	for _, w := range e.layout.windows() {
+		if w != e.Window {
+			w.save()
+		}
+	}
+	e.layout = &layout{window: e.Window}
+	e.relayout()
+}
+
+// grow the current window by delta cells
+func (e *Editor) resizeWindow(delta int, vertical bool) {
+	e.layout.resize(e.Window, delta, vertical)
+	e.relayout()
+	e.handleScrolling()
+}
+
+// handle the keys following Ctrl-W
+func (e *Editor) windowCommand() {
+	ev := e.pollKey()
+	switch ev.Key() {
+	case tcell.KeyLeft:
+		e.focusNeighbour(-1, 0)
+	case tcell.KeyRight:
+		e.focusNeighbour(1, 0)
+	case tcell.KeyUp:
+		e.focusNeighbour(0, -1)
+	case tcell.KeyDown:
+		e.focusNeighbour(0, 1)
+	case tcell.KeyCtrlW:
+		e.cycleWindow(1)
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'h':
+			e.focusNeighbour(-1, 0)
+		case 'l':
+			e.focusNeighbour(1, 0)
+		case 'k':
+			e.focusNeighbour(0, -1)
+		case 'j':
+			e.focusNeighbour(0, 1)
+		case 'w':
+			e.cycleWindow(1)
+		case 'W':
+			e.cycleWindow(-1)
+		case 's':
+			e.splitWindow(false)
+		case 'v':
+			e.splitWindow(true)
+		case 'c', 'q':
+			e.closeWindow()
+		case 'o':
+			e.onlyWindow()
+		case '+':
+			e.resizeWindow(1, false)
+		case '-':
+			e.resizeWindow(-1, false)
+		case '>':
+			e.resizeWindow(1, true)
+		case '<':
+			e.resizeWindow(-1, true)
+		}
+	}
+}
+
+// draw the document shown by w in its area of the screen
+func (e *Editor) drawWindow(w *Window) {
+	textStyle := e.theme.Text
+
+	// draw a cell of the text area, at a render column of a document line
+	setCell := func(renderX, y int, r rune, style tcell.Style) {
+		if renderX >= w.OffsetX && renderX < w.OffsetX+w.textWidth() {
+			e.Screen.SetContent(w.area.x+LineNumberWidth+(renderX-w.OffsetX), w.area.y+y-w.OffsetY, r, nil, style)
+		}
+	}
+
+	for i := w.OffsetY; i < w.InternalBuffer.LineCount() && i < w.OffsetY+w.textHeight(); i++ {
+		lineNumStr := fmt.Sprintf("%*d ", LineNumberWidth-1, i+1)
+		style := e.theme.Gutter
+		if i == w.InternalCursor.Y {
+			style = e.theme.CurrentLine
+		}
+		for j, r := range lineNumStr {
+			if j < LineNumberWidth && j < w.area.w {
+				e.Screen.SetContent(w.area.x+j, w.area.y+i-w.OffsetY, r, nil, style)
+			}
+		}
+
+		lineRunes := []rune(w.InternalBuffer.Line(i))
+		selStart, selEnd, selected := w.selectedRunes(i)
+		matches := e.highlightedMatches(w, i)
+		classes := w.highlight(i)
+		renderX := 0
+		for runeIdx := 0; runeIdx < len(lineRunes) && renderX < w.OffsetX+w.textWidth(); runeIdx++ {
+			r := lineRunes[runeIdx]
+			style := textStyle
+			if s, ok := e.theme.Syntax[classAt(classes, runeIdx)]; ok {
+				style = s
+			}
+			if selected && runeIdx >= selStart && runeIdx < selEnd {
+				style = e.theme.Selection
+			} else if inMatch(matches, runeIdx) {
+				style = e.theme.SearchMatch
+			}
+
+			charWidth := 1
+			if r == '\t' {
+				// Handle tab expansion
+				tabPos := renderX % buffer.TAB_SIZE
+				charWidth = buffer.TAB_SIZE - tabPos
+				for k := 0; k < charWidth; k++ {
+					setCell(renderX+k, i, ' ', style)
+				}
+			} else {
+				// Handle regular characters including wide ones
+				charWidth = runewidth.RuneWidth(r)
+				if charWidth == 0 {
+					charWidth = 1 // control characters
+				}
+				setCell(renderX, i, r, style)
+				// For wide characters, fill additional columns with spaces
+				for k := 1; k < charWidth; k++ {
+					setCell(renderX+k, i, ' ', style)
+				}
+			}
+			renderX += charWidth
+		}
+
+		// show that the newline is selected too
+		if selected && selEnd > len(lineRunes) {
+			setCell(renderX, i, ' ', e.theme.Selection)
+		}
+
+		// the other cursors are drawn as reversed cells
+		for _, c := range w.Cursors {
+			if c.Y != i {
+				continue
+			}
+			r := ' '
+			if c.X < len(lineRunes) && lineRunes[c.X] != '\t' {
+				r = lineRunes[c.X]
+			}
+			setCell(renderColumn(lineRunes, c.X), i, r, textStyle.Reverse(true))
+		}
+	}
+
+	// the window bar is only useful to tell the windows apart
+	if e.layout.window != nil {
+		return
+	}
+
+	barStyle := textStyle.Reverse(true)
+	if w == e.Window {
+		barStyle = e.theme.StatusBar.Bold(true)
+	}
+	bar := []rune(" " + w.name())
+	if w.fileChanged {
+		bar = append(bar, []rune(" [+]")...)
+	}
+	for x := range w.area.w {
+		r := ' '
+		if x < len(bar) {
+			r = bar[x]
+		}
+		e.Screen.SetContent(w.area.x+x, w.area.y+w.area.h-1, r, nil, barStyle)
+	}
+}
+
+// draw the separators between the windows that are side by side
+func (e *Editor) drawSeparators(l *layout) {
+	if l.window != nil {
+		return
+	}
+
+	if l.vertical {
+		x := l.second.area.x - 1
+		for y := l.area.y; y < l.area.y+l.area.h; y++ {
+			e.Screen.SetContent(x, y, '│', nil, e.theme.Text)
+		}
+	}
+	e.drawSeparators(l.first)
+	e.drawSeparators(l.second)
+}
+
+// the token class of the rune at x, from the classes of its line
+func classAt(classes []syntax.Class, x int) syntax.Class {
+	if x < len(classes) {
+		return classes[x]
+	}
+	return syntax.Plain
+}
@@ -2,16 +2,25 @@ package main
 
 import (
 	"flag"
+	"strings"
 
 	"github.com/eze-kiel/tide/editor"
-	"github.com/eze-kiel/tide/file"
 	"github.com/eze-kiel/tide/options"
+	"github.com/eze-kiel/tide/statusline"
+	"github.com/eze-kiel/tide/theme"
 )
 
 func main() {
+	// the status line of the user's file, unless another is given
+	statusLine, err := statusline.Load()
+	if err != nil {
+		panic(err)
+	}
+
 	var o options.Opts
 	flag.BoolVar(&o.AutoSaveOnSwitch, "autosave-on-switch", false, "enable autosave when switching modes")
-	flag.StringVar(&o.Theme, "color-theme", "dark", "set color theme (can be 'dark', 'light', 'valensole')")
+	flag.StringVar(&o.Theme, "color-theme", "dark", "set color theme (can be '"+strings.Join(theme.Names(), "', '")+"')")
+	flag.StringVar(&o.StatusLine, "status-line", statusLine, "set the segments of the status line, the ones after '|' being aligned on the right (can be '"+strings.Join(statusline.Names(), "', '")+"')")
 	flag.Parse()
 
 	if err := o.Verify(); err != nil {
@@ -23,17 +32,14 @@ func main() {
 		e.Crash(err)
 	}
 
-	if len(flag.Args()) > 0 {
-		e.Filename = flag.Arg(0)
-	}
 	defer e.Screen.Fini()
 
-	if file.Exists(e.Filename) {
-		e.InternalBuffer.Data, err = file.Read(e.Filename)
-		if err != nil {
+	for _, fname := range flag.Args() {
+		if err := e.Open(fname); err != nil {
 			e.Crash(err)
 		}
 	}
+	e.SwitchDocument(0)
 
 	if err := e.Run(); err != nil {
 		e.Crash(err)
@@ -1,23 +1,24 @@
 package options
 
 import (
-	"fmt"
+	"github.com/eze-kiel/tide/statusline"
+	"github.com/eze-kiel/tide/theme"
 )
 
 // Opts contains options that are provided using command-line flags
 type Opts struct {
 	AutoSaveOnSwitch bool
 	Theme            string
+	StatusLine       string // the segments of the status line
 }
 
 func (o Opts) Verify() error {
-	// check that the theme provided is supported
-	// the themes are set up in editor/themes.go so any addition there should be
-	// backported here to avoid errors
-	switch o.Theme {
-	case "dark", "light", "valensole":
-		return nil
-	default:
-		return fmt.Errorf("theme '%s' is not supported", o.Theme)
+	// check that the theme provided is one of the bundled themes or of the
+	// user's themes, and that its file is valid
+	if _, err := theme.Load(o.Theme); err != nil {
+		return err
 	}
+
+	_, err := statusline.Parse(o.StatusLine)
+	return err
 }
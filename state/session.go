@@ -0,0 +1,66 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Session is what tide keeps from one run to the next, whatever the files
+// being edited
+type Session struct {
+	Registers map[string]Register `json:"registers"` // the named registers, macros included
+}
+
+// Register is the content of a register, and the kind of selection it comes
+// from
+type Register struct {
+	Text string `json:"text"`
+	Kind int    `json:"kind"`
+}
+
+// SessionPath returns the path of the session file
+func SessionPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session.json"), nil
+}
+
+// SaveSession writes the session file
+func SaveSession(s Session) error {
+	path, err := SessionPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// LoadSession reads the session file. It returns an empty session when there
+// is none
+func LoadSession() (Session, error) {
+	path, err := SessionPath()
+	if err != nil {
+		return Session{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Session{}, nil
+	}
+	if err != nil {
+		return Session{}, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Session{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/eze-kiel/tide/actions"
+)
+
+// Dir returns the directory where tide keeps its state between sessions,
+// following the XDG base directory specification
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "tide"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "tide"), nil
+}
+
+// undoFile is the content of a persisted undo history
+type undoFile struct {
+	Path    string           `json:"path"`
+	Hash    string           `json:"hash"` // hash of the file content the history ends up on
+	History *actions.History `json:"history"`
+}
+
+// UndoPath returns the path of the undo file of fname, which is keyed by the
+// absolute path of fname
+func UndoPath(fname string) (string, error) {
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "undo", hash(abs)+".json"), nil
+}
+
+// SaveUndo writes the undo history of fname, whose content is content
+func SaveUndo(fname, content string, h *actions.History) error {
+	path, err := UndoPath(fname)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(fname)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(undoFile{
+		Path:    abs,
+		Hash:    hash(content),
+		History: h,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// writeFile writes data to path, creating the directories it is in. It goes
+// through a temporary file first so a crash never leaves a truncated file
+// behind
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadUndo reads the undo history of fname, whose content is content. It
+// returns a nil history when there is none, and discards the persisted one if
+// the file has been modified outside of tide since it was written
+func LoadUndo(fname, content string) (*actions.History, error) {
+	path, err := UndoPath(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u := undoFile{History: actions.NewHistory()}
+	if err := json.Unmarshal(data, &u); err != nil || u.Hash != hash(content) {
+		return nil, os.Remove(path)
+	}
+	return u.History, nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package statusline
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Default is the status line shown when none is configured
+const Default = "mode recording file modified | selection filetype eol encoding position percent"
+
+// Info is what the segments show, as the editor sees it when drawing the
+// status line
+type Info struct {
+	Mode      string
+	Recording string // the recording message, when recording a macro
+	File      string
+	Modified  bool
+
+	Line, Lines int // the line of the cursor from 1, and the number of lines
+	Column      int // the rune column of the cursor from 1
+	Display     int // the display column of the cursor from 1, tabs and wide runes expanded
+
+	FileType   string
+	LineEnding string
+	Encoding   string
+	Selection  string // the size of the selection, if any
+}
+
+// Segment returns the text of a part of the status line, empty when it has
+// nothing to show
+type Segment func(i Info) string
+
+// Segments are the segments available, by name
+var Segments = map[string]Segment{
+	"mode":      func(i Info) string { return i.Mode },
+	"recording": func(i Info) string { return i.Recording },
+	"file":      func(i Info) string { return i.File },
+	"modified": func(i Info) string {
+		if i.Modified {
+			return "[+]"
+		}
+		return ""
+	},
+	"position": func(i Info) string {
+		if i.Display != i.Column {
+			return fmt.Sprintf("%d:%d-%d", i.Line, i.Column, i.Display)
+		}
+		return fmt.Sprintf("%d:%d", i.Line, i.Column)
+	},
+	"percent": func(i Info) string {
+		if i.Lines == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d%%", i.Line*100/i.Lines)
+	},
+	"filetype":  func(i Info) string { return i.FileType },
+	"eol":       func(i Info) string { return i.LineEnding },
+	"encoding":  func(i Info) string { return i.Encoding },
+	"selection": func(i Info) string { return i.Selection },
+}
+
+// Line is a status line, made of the segments aligned on the left and of the
+// ones aligned on the right
+type Line struct {
+	Left, Right []string
+}
+
+// Parse reads a status line made of segment names separated by spaces or
+// commas. The segments after a | are aligned on the right
+func Parse(spec string) (Line, error) {
+	var l Line
+	left, right, _ := strings.Cut(spec, "|")
+	for _, part := range []struct {
+		names *[]string
+		spec  string
+	}{
+		{&l.Left, left},
+		{&l.Right, right},
+	} {
+		for _, name := range strings.FieldsFunc(part.spec, func(r rune) bool { return r == ' ' || r == ',' }) {
+			if _, ok := Segments[name]; !ok {
+				return l, fmt.Errorf("status line segment '%s' is not supported, the available segments are: %s", name, strings.Join(Names(), ", "))
+			}
+			*part.names = append(*part.names, name)
+		}
+	}
+	return l, nil
+}
+
+// File returns the path of the file giving the user's status line, next to
+// the directory of the themes
+func File() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "tide", "status-line"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "tide", "status-line"), nil
+}
+
+// Load returns the status line written in the user's file, where the segments
+// can be on several lines, or the default one when there is no such file
+func Load() (string, error) {
+	path, err := File()
+	if err != nil {
+		return Default, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(data)), " "), nil
+}
+
+// Names returns the names of the segments
+func Names() []string {
+	names := make([]string, 0, len(Segments))
+	for name := range Segments {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Part is the text a segment shows
+type Part struct {
+	Name string
+	Text string
+}
+
+// Render returns the parts shown on the left and on the right, the segments
+// with nothing to show being left out
+func (l Line) Render(i Info) (left, right []Part) {
+	return render(l.Left, i), render(l.Right, i)
+}
+
+func render(names []string, i Info) []Part {
+	var parts []Part
+	for _, name := range names {
+		if text := Segments[name](i); text != "" {
+			parts = append(parts, Part{Name: name, Text: text})
+		}
+	}
+	return parts
+}
@@ -4,13 +4,54 @@ const (
 	EditMode   = "INSERT"
 	VisualMode = "VISUAL"
 
-	SavedMsg          = "Saved to: "
-	AutoSavedMsg      = "Automatically saved to: "
-	CannotSaveErr     = "Cannot save: "
-	FileModified      = "File has been modified, override with q! or save"
-	UnknownCommandErr = "Unknown command: "
-	NothingToDoMsg    = "Nothing to do"
-	NoMoreUndoMsg     = "No more things to undo"
+	SavedMsg             = "Saved to: "
+	AutoSavedMsg         = "Automatically saved to: "
+	CannotSaveErr        = "Cannot save: "
+	CannotSaveUndoErr    = "Cannot save undo history: "
+	CannotLoadUndoErr    = "Cannot load undo history: "
+	FileModified         = "File has been modified, override with q! or save"
+	OtherFileModified    = "%s has been modified, override with q! or save"
+	CannotOpenErr        = "Cannot open: "
+	MissingFilenameErr   = "Missing file name"
+	NoSuchBufferErr      = "No such buffer"
+	PressAnyKeyMsg       = "Press any key to continue"
+	NoName               = "[No Name]"
+	LastWindowErr        = "Cannot close the last window"
+	MissingSizeErr       = "Missing size"
+	UnknownCommandErr    = "Unknown command: "
+	NothingToDoMsg       = "Nothing to do"
+	NoMoreUndoMsg        = "No more things to undo"
+	NoMoreRedoMsg        = "No more things to redo"
+	HistoryStateMsg      = "Change %d of %d"
+	NoWordUnderCursorErr = "No word under the cursor"
+	NoOtherOccurrenceMsg = "No other occurrence"
+	InvalidPatternErr    = "Invalid pattern: "
+	PatternNotFoundErr   = "Pattern not found: "
+	NoPreviousSearchErr  = "No previous search"
+	SearchCountMsg       = "%c%s [%d/%d]"
+	SearchWrappedMsg     = "Search wrapped, "
+	InvalidRangeErr      = "Invalid range"
+	InvalidAddressErr    = "Invalid address"
+	InvalidArgumentErr   = "Invalid argument: "
+	MarkNotSetErr        = "Mark not set"
+	MoveIntoItselfErr    = "Cannot move a range of lines into itself"
+	InvalidFlagErr       = "Invalid flag: %c"
+	SubstituteMsg        = "%d substitutions on %d lines"
+	ConfirmReplaceMsg    = "Replace with %s (y/n/a/l/q)?"
+	RecordingMsg         = "recording @%c"
+	InvalidRegisterErr   = "Invalid register: %c"
+	EmptyRegisterErr     = "Register %c is empty"
+	MacroTooDeepErr      = "Macros are nested too deep"
+	NothingPastedErr     = "Nothing pasted to cycle"
+	RingEntryMsg         = "Yank %d of %d"
+	RegistersHeader      = "Type Name Content"
+	CannotCopyErr        = "Cannot copy to the clipboard: "
+	CannotPasteErr       = "Cannot paste from the clipboard: "
+	CannotSaveSessionErr = "Cannot save session: "
+	CannotLoadSessionErr = "Cannot load session: "
+	SelectedCharsMsg     = "%d chars"
+	SelectedLinesMsg     = "%d lines"
+	SelectedBlockMsg     = "%dx%d block"
 
 	Comment = "//"
 )
@@ -0,0 +1,54 @@
+package syntax
+
+// Highlighter highlights the lines of a document with a grammar. It keeps the
+// classes of the lines it highlighted and the state they leave, so that only
+// the lines from a change on are highlighted again, and only once they are
+// needed
+type Highlighter struct {
+	grammar *Grammar
+	lines   []highlightedLine // the lines from the first one, up to the first change
+}
+
+type highlightedLine struct {
+	classes []Class
+	end     State
+}
+
+// NewHighlighter returns a highlighter using g, which can be nil for documents
+// without grammar
+func NewHighlighter(g *Grammar) *Highlighter {
+	return &Highlighter{grammar: g}
+}
+
+// Name returns the name of the grammar, or "" without grammar
+func (h *Highlighter) Name() string {
+	if h.grammar == nil {
+		return ""
+	}
+	return h.grammar.Name
+}
+
+// Invalidate forgets about the lines from y on, which changed
+func (h *Highlighter) Invalidate(y int) {
+	if y < len(h.lines) {
+		h.lines = h.lines[:max(y, 0)]
+	}
+}
+
+// Line returns the classes of the runes of line y, or nil without grammar.
+// line gives the content of the lines of the document, which are highlighted
+// from the first one that changed up to y
+func (h *Highlighter) Line(y int, line func(y int) string) []Class {
+	if h.grammar == nil {
+		return nil
+	}
+	for i := len(h.lines); i <= y; i++ {
+		state := NoRegion
+		if i > 0 {
+			state = h.lines[i-1].end
+		}
+		classes, end := h.grammar.Highlight(line(i), state)
+		h.lines = append(h.lines, highlightedLine{classes: classes, end: end})
+	}
+	return h.lines[y].classes
+}
@@ -0,0 +1,272 @@
+package syntax
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Class is the kind of token a rune is part of, which themes give a style to
+type Class int
+
+const (
+	Plain Class = iota
+	Comment
+	Keyword
+	Type
+	Function
+	String
+	Number
+	Constant
+	Operator
+	Variable
+	Key
+	Heading
+	Emphasis
+	Link
+	Special
+)
+
+// ClassNames are the names of the classes, as used in grammars and themes
+var ClassNames = []string{
+	Plain:    "plain",
+	Comment:  "comment",
+	Keyword:  "keyword",
+	Type:     "type",
+	Function: "function",
+	String:   "string",
+	Number:   "number",
+	Constant: "constant",
+	Operator: "operator",
+	Variable: "variable",
+	Key:      "key",
+	Heading:  "heading",
+	Emphasis: "emphasis",
+	Link:     "link",
+	Special:  "special",
+}
+
+// ParseClass returns the class called name
+func ParseClass(name string) (Class, bool) {
+	for c, n := range ClassNames {
+		if n == name {
+			return Class(c), true
+		}
+	}
+	return Plain, false
+}
+
+// Grammar tells how to split the lines of a kind of file into tokens. Its
+// rules are either a match, a pattern the tokens of the class match, or a
+// region going from a start pattern to an end one, possibly over several
+// lines, like strings or comments. Regions come first, and a rune is given
+// the class of the first match rule matching it outside of them
+type Grammar struct {
+	Name   string `json:"name"`
+	Files  string `json:"files"`  // the pattern of the names of the files it applies to
+	Header string `json:"header"` // the pattern of the first line of the files it applies to
+	Rules  []Rule `json:"rules"`
+
+	files, header *regexp.Regexp
+}
+
+type Rule struct {
+	Class string `json:"class"`
+	Match string `json:"match,omitempty"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+	Skip  string `json:"skip,omitempty"` // what cannot end the region, like an escaped quote
+
+	class                   Class
+	match, start, end, skip *regexp.Regexp
+}
+
+// State is what a line leaves open for the next one: the index of the region
+// rule it ends in, or -1
+type State int
+
+const NoRegion State = -1
+
+// Load reads a grammar written as JSON
+func Load(data []byte) (*Grammar, error) {
+	var g Grammar
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+
+	var err error
+	compile := func(pattern string) *regexp.Regexp {
+		if pattern == "" || err != nil {
+			return nil
+		}
+		var re *regexp.Regexp
+		re, err = regexp.Compile(pattern)
+		return re
+	}
+
+	g.files = compile(g.Files)
+	g.header = compile(g.Header)
+	for i := range g.Rules {
+		r := &g.Rules[i]
+		class, ok := ParseClass(r.Class)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown class %q", g.Name, r.Class)
+		}
+		r.class = class
+		r.match, r.start, r.end, r.skip = compile(r.Match), compile(r.Start), compile(r.End), compile(r.Skip)
+		if err == nil && (r.match == nil) == (r.start == nil || r.end == nil) {
+			err = fmt.Errorf("%s: a rule needs either a match, or a start and an end", g.Name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+//go:embed grammars/*.json
+var bundled embed.FS
+
+var grammars []*Grammar
+
+func init() {
+	entries, _ := bundled.ReadDir("grammars")
+	for _, entry := range entries {
+		data, err := bundled.ReadFile(path.Join("grammars", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		g, err := Load(data)
+		if err != nil {
+			panic(err)
+		}
+		grammars = append(grammars, g)
+	}
+}
+
+// Find returns the bundled grammar of a file from its name, or from its first
+// line, or nil if there is none
+func Find(fname, firstLine string) *Grammar {
+	for _, g := range grammars {
+		if g.files != nil && g.files.MatchString(fname) {
+			return g
+		}
+	}
+	for _, g := range grammars {
+		if g.header != nil && g.header.MatchString(firstLine) {
+			return g
+		}
+	}
+	return nil
+}
+
+// Highlight returns the class of every rune of line, given the state the
+// previous line left, and the state it leaves for the next one
+func (g *Grammar) Highlight(line string, state State) ([]Class, State) {
+	classes := make([]Class, len(line)) // by byte until the end
+	inRegion := make([]bool, len(line))
+	paint := func(from, to int, c Class) {
+		for i := from; i < to; i++ {
+			classes[i] = c
+			inRegion[i] = true
+		}
+	}
+
+	pos := 0
+	if state != NoRegion {
+		r := &g.Rules[state]
+		end := r.regionEnd(line, 0)
+		if end < 0 {
+			paint(0, len(line), r.class)
+			return runeClasses(line, classes), state
+		}
+		paint(0, end, r.class)
+		pos = end
+	}
+
+	// regions, the one starting first at each step
+	for state = NoRegion; pos <= len(line); {
+		best, start, afterStart := -1, len(line)+1, 0
+		for i := range g.Rules {
+			r := &g.Rules[i]
+			if r.start == nil {
+				continue
+			}
+			for _, m := range r.start.FindAllStringIndex(line, -1) {
+				if m[0] >= pos {
+					if m[0] < start {
+						best, start, afterStart = i, m[0], m[1]
+					}
+					break
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		r := &g.Rules[best]
+		end := r.regionEnd(line, afterStart)
+		if end < 0 {
+			paint(start, len(line), r.class)
+			state = State(best)
+			break
+		}
+		paint(start, end, r.class)
+		pos = max(end, start+1)
+	}
+
+	// matches, around the regions
+	matched := make([]bool, len(line))
+	for i := range g.Rules {
+		r := &g.Rules[i]
+		if r.match == nil {
+			continue
+		}
+		for _, m := range r.match.FindAllStringIndex(line, -1) {
+			for b := m[0]; b < m[1]; b++ {
+				if !inRegion[b] && !matched[b] {
+					classes[b] = r.class
+					matched[b] = true
+				}
+			}
+		}
+	}
+	return runeClasses(line, classes), state
+}
+
+// regionEnd returns the byte offset after the end of the region, looking for
+// it from the from offset, or -1 if the region does not end on the line
+func (r *Rule) regionEnd(line string, from int) int {
+	var skips [][]int
+	if r.skip != nil {
+		skips = r.skip.FindAllStringIndex(line, -1)
+	}
+
+next:
+	for _, m := range r.end.FindAllStringIndex(line, -1) {
+		if m[0] < from {
+			continue
+		}
+		for _, s := range skips {
+			if s[0] >= from && m[0] >= s[0] && m[0] < s[1] {
+				continue next
+			}
+		}
+		return m[1]
+	}
+	return -1
+}
+
+// runeClasses turns the classes of the bytes of line into the ones of its
+// runes
+func runeClasses(line string, byteClasses []Class) []Class {
+	classes := make([]Class, 0, utf8.RuneCountInString(line))
+	for i := range line {
+		classes = append(classes, byteClasses[i])
+	}
+	return classes
+}
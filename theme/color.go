@@ -0,0 +1,94 @@
+package theme
+
+import (
+	"os"
+
+	"github.com/eze-kiel/tide/syntax"
+	"github.com/gdamore/tcell/v2"
+)
+
+// TrueColor is the number of colors of a terminal showing any RGB color
+const TrueColor = 1 << 24
+
+// Colors returns how many colors the themes can use on a terminal able to show
+// n of them. None can be used when NO_COLOR is set, see https://no-color.org
+func Colors(n int) int {
+	if os.Getenv("NO_COLOR") != "" {
+		return 0
+	}
+	return n
+}
+
+// ForScreen returns the theme as shown by the screen s, given the colors it
+// reports and NO_COLOR
+func (t *Theme) ForScreen(s tcell.Screen) *Theme {
+	return t.ForColors(Colors(s.Colors()))
+}
+
+// ForColors returns the theme as shown by a terminal with n colors. With 256
+// or 16 colors, each color is replaced by the closest one of the terminal's
+// palette. With fewer than 8, only the attributes are kept, and the styles
+// standing out by their background, like the selection, are reversed
+func (t *Theme) ForColors(n int) *Theme {
+	if n >= TrueColor {
+		return t
+	}
+
+	var palette []tcell.Color
+	if n >= 8 {
+		palette = make([]tcell.Color, min(n, 256))
+		for i := range palette {
+			palette[i] = tcell.PaletteColor(i)
+		}
+	}
+
+	down := *t
+	down.Text = downsample(t.Text, palette, t.Text)
+	for _, st := range []*tcell.Style{
+		&down.Gutter,
+		&down.CurrentLine,
+		&down.Selection,
+		&down.StatusBar,
+		&down.CommandLine,
+		&down.SearchMatch,
+		&down.Warning,
+		&down.Error,
+	} {
+		*st = downsample(*st, palette, t.Text)
+	}
+
+	down.Syntax = make(map[syntax.Class]tcell.Style, len(t.Syntax))
+	for class, st := range t.Syntax {
+		down.Syntax[class] = downsample(st, palette, t.Text)
+	}
+	return &down
+}
+
+// downsample returns st with the colors of palette. A style whose background
+// stood out from the one of text is reversed when it no longer does, and text
+// that would be as dark as its background gets the foreground of text
+func downsample(st tcell.Style, palette []tcell.Color, text tcell.Style) tcell.Style {
+	fg, bg, _ := st.Decompose()
+	textFg, textBg, _ := text.Decompose()
+
+	down := st.Foreground(closest(fg, palette)).Background(closest(bg, palette))
+	if fg != bg && len(palette) > 0 && closest(fg, palette) == closest(bg, palette) {
+		down = down.Foreground(closest(textFg, palette))
+	}
+	if bg != textBg && closest(bg, palette) == closest(textBg, palette) {
+		down = down.Reverse(true)
+	}
+	return down
+}
+
+// closest returns the color of palette closest to c, or the default color of
+// the terminal when the palette is empty
+func closest(c tcell.Color, palette []tcell.Color) tcell.Color {
+	switch {
+	case len(palette) == 0 || !c.Valid():
+		return tcell.ColorDefault
+	case !c.IsRGB() && int(c-tcell.ColorValid) < len(palette):
+		return c
+	}
+	return tcell.FindColor(c, palette)
+}
@@ -0,0 +1,147 @@
+package theme
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// render draws a line of text, the current line number and a selection with
+// the styles of t, and returns the screen they were drawn on
+func render(t *testing.T, th *Theme) tcell.SimulationScreen {
+	t.Helper()
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Fini)
+	s.SetSize(10, 1)
+
+	s.SetContent(0, 0, 'a', nil, th.Text)
+	s.SetContent(1, 0, '1', nil, th.CurrentLine)
+	s.SetContent(2, 0, 'b', nil, th.Selection)
+	s.Show()
+	return s
+}
+
+func TestForColors(t *testing.T) {
+	dark, err := Load("dark")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type colors struct{ fg, bg tcell.Color }
+	for _, tc := range []struct {
+		name      string
+		colors    int
+		text      colors
+		selection colors
+	}{
+		{
+			name:      "truecolor",
+			colors:    TrueColor,
+			text:      colors{tcell.ColorWhiteSmoke, tcell.ColorBlack},
+			selection: colors{tcell.ColorWhiteSmoke, tcell.ColorRebeccaPurple},
+		},
+		{
+			name:      "256 colors",
+			colors:    256,
+			text:      colors{tcell.PaletteColor(255), tcell.ColorBlack},
+			selection: colors{tcell.PaletteColor(255), tcell.PaletteColor(54)},
+		},
+		{
+			name:      "16 colors",
+			colors:    16,
+			text:      colors{tcell.ColorWhite, tcell.ColorBlack},
+			selection: colors{tcell.ColorWhite, tcell.ColorPurple},
+		},
+		{
+			name:      "8 colors",
+			colors:    8,
+			text:      colors{tcell.ColorSilver, tcell.ColorBlack},
+			selection: colors{tcell.ColorSilver, tcell.ColorPurple},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := render(t, dark.ForColors(tc.colors))
+			for x, want := range []colors{tc.text, tc.selection, tc.selection} {
+				_, _, style, _ := s.GetContent(x, 0)
+				fg, bg, _ := style.Decompose()
+				if fg != want.fg || bg != want.bg {
+					t.Errorf("cell %d: got fg %v bg %v, want fg %v bg %v", x, fg, bg, want.fg, want.bg)
+				}
+			}
+		})
+	}
+}
+
+func TestNoColor(t *testing.T) {
+	dark, err := Load("dark")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	n := Colors(256)
+	if n != 0 {
+		t.Fatalf("got %d colors with NO_COLOR set, want 0", n)
+	}
+
+	s := render(t, dark.ForColors(n))
+	for x, want := range []tcell.AttrMask{0, tcell.AttrReverse | tcell.AttrBold, tcell.AttrReverse} {
+		_, _, style, _ := s.GetContent(x, 0)
+		fg, bg, attrs := style.Decompose()
+		if fg != tcell.ColorDefault || bg != tcell.ColorDefault {
+			t.Errorf("cell %d: got fg %v bg %v, want the default colors", x, fg, bg)
+		}
+		if attrs != want {
+			t.Errorf("cell %d: got attributes %v, want %v", x, attrs, want)
+		}
+	}
+}
+
+// screen is a simulated screen reporting colors colors
+type screen struct {
+	tcell.SimulationScreen
+	colors int
+}
+
+func (s screen) Colors() int {
+	return s.colors
+}
+
+func TestForScreen(t *testing.T) {
+	dark, err := Load("dark")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		colors  int
+		noColor bool
+		want    int // the colors the theme is shown with
+	}{
+		{"no color", 0, false, 0},
+		{"8 colors", 8, false, 8},
+		{"256 colors", 256, false, 256},
+		{"truecolor", TrueColor, false, TrueColor},
+		{"no color with NO_COLOR", 0, true, 0},
+		{"8 colors with NO_COLOR", 8, true, 0},
+		{"256 colors with NO_COLOR", 256, true, 0},
+		{"truecolor with NO_COLOR", TrueColor, true, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			if tc.noColor {
+				t.Setenv("NO_COLOR", "1")
+			}
+
+			got := dark.ForScreen(screen{tcell.NewSimulationScreen(""), tc.colors})
+			want := dark.ForColors(tc.want)
+			if got.Text != want.Text || got.CurrentLine != want.CurrentLine || got.Selection != want.Selection {
+				t.Errorf("got the theme for %d colors, want the one for %d", tc.colors, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package theme
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/eze-kiel/tide/syntax"
+	"github.com/gdamore/tcell/v2"
+)
+
+// Theme is the styles the editor is drawn with
+type Theme struct {
+	Name string
+
+	Text        tcell.Style // the text, and the background of the whole editor
+	Gutter      tcell.Style // the line numbers
+	CurrentLine tcell.Style // the number of the line the cursor is on
+	Selection   tcell.Style
+	StatusBar   tcell.Style // the mode, and the bar of the current window
+	CommandLine tcell.Style // the command line and the messages
+	SearchMatch tcell.Style
+	Warning     tcell.Style // the warning messages
+	Error       tcell.Style // the error messages
+
+	Syntax map[syntax.Class]tcell.Style // the token classes, plain text being Text
+}
+
+// file is a theme as written in a theme file. Colors are names, like
+// "rebeccapurple", or #rrggbb values. The colors a style does not give are
+// the foreground and background ones
+type file struct {
+	Foreground  string           `json:"foreground"`
+	Background  string           `json:"background"`
+	Gutter      style            `json:"gutter"`
+	CurrentLine style            `json:"current-line"`
+	Selection   style            `json:"selection"`
+	StatusBar   style            `json:"status-bar"`
+	CommandLine style            `json:"command-line"`
+	SearchMatch style            `json:"search-match"`
+	Warning     style            `json:"warning"`
+	Error       style            `json:"error"`
+	Syntax      map[string]style `json:"syntax"`
+}
+
+type style struct {
+	Fg        string `json:"fg"`
+	Bg        string `json:"bg"`
+	Bold      bool   `json:"bold"`
+	Italic    bool   `json:"italic"`
+	Underline bool   `json:"underline"`
+	Reverse   bool   `json:"reverse"`
+}
+
+//go:embed themes/*.json
+var bundled embed.FS
+
+// Dir returns the directory of the user's themes, following the XDG base
+// directory specification
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "tide", "themes"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "tide", "themes"), nil
+}
+
+// Names returns the names of the available themes: the bundled ones and the
+// ones found in the user's directory
+func Names() []string {
+	var names []string
+	add := func(entries []fs.DirEntry) {
+		for _, entry := range entries {
+			if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+				names = append(names, name)
+			}
+		}
+	}
+
+	entries, _ := bundled.ReadDir("themes")
+	add(entries)
+	if dir, err := Dir(); err == nil {
+		entries, _ := os.ReadDir(dir)
+		add(entries)
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// Load returns the theme called name. A theme of the user's directory comes
+// before a bundled one of the same name
+func Load(name string) (*Theme, error) {
+	if !slices.Contains(Names(), name) {
+		return nil, fmt.Errorf("theme '%s' is not supported, the available themes are: %s", name, strings.Join(Names(), ", "))
+	}
+
+	var data []byte
+	dir, err := Dir()
+	if err == nil {
+		data, err = os.ReadFile(filepath.Join(dir, name+".json"))
+	}
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if data, err = bundled.ReadFile("themes/" + name + ".json"); err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := Parse(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("theme '%s': %w", name, err)
+	}
+	return t, nil
+}
+
+// Parse reads a theme file
+func Parse(name string, data []byte) (*Theme, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+
+	fg, err := parseColor(f.Foreground)
+	if err != nil {
+		return nil, err
+	}
+	bg, err := parseColor(f.Background)
+	if err != nil {
+		return nil, err
+	}
+	text := tcell.StyleDefault.Foreground(fg).Background(bg)
+
+	t := &Theme{Name: name, Text: text, Syntax: make(map[syntax.Class]tcell.Style)}
+	for _, s := range []struct {
+		style *tcell.Style
+		spec  style
+	}{
+		{&t.Gutter, f.Gutter},
+		{&t.CurrentLine, f.CurrentLine},
+		{&t.Selection, f.Selection},
+		{&t.StatusBar, f.StatusBar},
+		{&t.CommandLine, f.CommandLine},
+		{&t.SearchMatch, f.SearchMatch},
+		{&t.Warning, f.Warning},
+		{&t.Error, f.Error},
+	} {
+		if *s.style, err = s.spec.resolve(text); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, spec := range f.Syntax {
+		class, ok := syntax.ParseClass(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown token class %q", name)
+		}
+		if t.Syntax[class], err = spec.resolve(text); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// resolve returns the style s describes, the colors it does not give being
+// the ones of base
+func (s style) resolve(base tcell.Style) (tcell.Style, error) {
+	st := base.Bold(s.Bold).Italic(s.Italic).Underline(s.Underline).Reverse(s.Reverse)
+	if s.Fg != "" {
+		c, err := parseColor(s.Fg)
+		if err != nil {
+			return st, err
+		}
+		st = st.Foreground(c)
+	}
+	if s.Bg != "" {
+		c, err := parseColor(s.Bg)
+		if err != nil {
+			return st, err
+		}
+		st = st.Background(c)
+	}
+	return st, nil
+}
+
+// parseColor reads a color name or a #rrggbb value. An empty color is the
+// default one of the terminal
+func parseColor(name string) (tcell.Color, error) {
+	if name == "" || name == "default" {
+		return tcell.ColorDefault, nil
+	}
+	c := tcell.GetColor(strings.ToLower(name))
+	if c == tcell.ColorDefault {
+		return c, fmt.Errorf("unknown color %q", name)
+	}
+	return c, nil
+}